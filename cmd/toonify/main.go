@@ -10,6 +10,9 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// specVersion selects the TOON syntax for both directions
+var specVersion string
+
 var rootCmd = &cobra.Command{
 	Use:   "toonify",
 	Short: "Convert between JSON and TOON formats",
@@ -77,17 +80,30 @@ var decodeCmd = &cobra.Command{
 }
 
 func encodeJSON(jsonData []byte) ([]byte, error) {
-	var data interface{}
-	if err := json.Unmarshal(jsonData, &data); err != nil {
+	// Keep the key order and the exact numbers of the JSON document
+	data, err := toonify.UnmarshalOrderedJSONNumbers(jsonData)
+	if err != nil {
 		return nil, fmt.Errorf("invalid JSON: %v", err)
 	}
 
-	return toonify.EncodeBytes(data)
+	opts := toonify.DefaultEncodeOptions()
+	opts.SpecVersion = specVersion
+
+	result, err := toonify.EncodeWithOptions(data, opts)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(result), nil
 }
 
 func decodeToon(toonData []byte) ([]byte, error) {
+	opts := toonify.DefaultDecodeOptions()
+	opts.PreserveOrder = true
+	opts.UseNumber = true
+	opts.SpecVersion = specVersion
+
 	var data interface{}
-	if err := toonify.DecodeBytes(toonData, &data); err != nil {
+	if err := toonify.DecodeWithOptions(string(toonData), &data, opts); err != nil {
 		return nil, fmt.Errorf("invalid TOON: %v", err)
 	}
 
@@ -95,6 +111,8 @@ func decodeToon(toonData []byte) ([]byte, error) {
 }
 
 func main() {
+	rootCmd.PersistentFlags().StringVar(&specVersion, "spec", toonify.SpecVersion3,
+		`TOON spec version: "3", "2" or "legacy" for files written by toonify 1.0`)
 	rootCmd.AddCommand(encodeCmd)
 	rootCmd.AddCommand(decodeCmd)
 
@@ -0,0 +1,156 @@
+package conformance
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fixturesDir holds one directory per fixture suite, each in the spec
+// repository's layout: encode/*.json and decode/*.json
+const fixturesDir = "../testdata/fixtures"
+
+// Fixture suites. The toonify suite is written for this package and always
+// runs; the official spec suite runs when its files are copied into
+// testdata/fixtures/spec.
+const (
+	suiteToonify = "toonify"
+	suiteSpec    = "spec"
+)
+
+type sectionResult struct {
+	passed  int
+	total   int
+	skipped int
+}
+
+func TestConformance(t *testing.T) {
+	for _, suite := range []string{suiteToonify, suiteSpec} {
+		dir := filepath.Join(fixturesDir, suite)
+		if _, err := os.Stat(dir); err != nil {
+			require.NotEqual(t, suiteToonify, suite, "no toonify fixtures found")
+			t.Logf("%s fixtures not found in %s, skipped", suite, dir)
+			continue
+		}
+
+		t.Run(suite, func(t *testing.T) {
+			results := map[string]*sectionResult{}
+			versions := map[string]bool{}
+			runSuite(t, dir, results, versions)
+			t.Log(conformanceReport(suite, results, versions))
+		})
+	}
+}
+
+// runSuite runs the encode and decode fixtures under dir, counting results
+// per spec section
+func runSuite(t *testing.T, dir string, results map[string]*sectionResult, versions map[string]bool) {
+	for _, category := range []string{CategoryEncode, CategoryDecode} {
+		fixtures, err := LoadFixtures(filepath.Join(dir, category))
+		require.NoError(t, err)
+		require.NotEmpty(t, fixtures, "no %s fixtures found", category)
+
+		for _, fixture := range fixtures {
+			fixture := fixture
+			if fixture.Version != "" {
+				versions[fixture.Version] = true
+			}
+			name := category + "/" + strings.TrimSuffix(filepath.Base(fixture.File), ".json")
+
+			t.Run(name, func(t *testing.T) {
+				for i := range fixture.Tests {
+					c := &fixture.Tests[i]
+
+					section := c.SpecSection
+					if section == "" {
+						section = "unspecified"
+					}
+					if results[section] == nil {
+						results[section] = &sectionResult{}
+					}
+
+					// Cases for a newer spec are reported, not failed
+					if !c.Supported() {
+						results[section].skipped++
+						t.Run(c.Name, func(t *testing.T) {
+							t.Skipf("requires spec %s, supported up to %s", c.MinSpecVersion, SupportedSpecVersion)
+						})
+						continue
+					}
+
+					passed := t.Run(c.Name, func(t *testing.T) {
+						if err := fixture.Run(c); err != nil {
+							t.Error(err)
+						}
+					})
+					results[section].total++
+					if passed {
+						results[section].passed++
+					}
+				}
+			})
+		}
+	}
+}
+
+// conformanceReport summarises the results of a suite per spec section
+func conformanceReport(suite string, results map[string]*sectionResult, versions map[string]bool) string {
+	var specVersions []string
+	for version := range versions {
+		specVersions = append(specVersions, version)
+	}
+	sort.Slice(specVersions, func(i, j int) bool { return versionLess(specVersions[i], specVersions[j]) })
+
+	sections := make([]string, 0, len(results))
+	for section := range results {
+		sections = append(sections, section)
+	}
+	sort.Slice(sections, func(i, j int) bool { return versionLess(sections[i], sections[j]) })
+
+	var b strings.Builder
+	switch {
+	case suite == suiteToonify:
+		b.WriteString("toonify fixtures (in-house tests, not the official spec suite):\n")
+	case len(specVersions) > 0:
+		fmt.Fprintf(&b, "%s fixtures, spec %s:\n", suite, strings.Join(specVersions, ", "))
+	default:
+		fmt.Fprintf(&b, "%s fixtures:\n", suite)
+	}
+	for _, section := range sections {
+		r := results[section]
+		status := "PASS"
+		if r.passed != r.total {
+			status = "FAIL"
+		}
+		fmt.Fprintf(&b, "  §%-6s %3d/%-3d %s", section, r.passed, r.total, status)
+		if r.skipped > 0 {
+			fmt.Fprintf(&b, " (%d skipped)", r.skipped)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func TestCaseSupported(t *testing.T) {
+	tests := []struct {
+		minSpecVersion string
+		expected       bool
+	}{
+		{"", true},
+		{"2.1", true},
+		{"3", true},
+		{"3.0", true},
+		{"3.1", false},
+		{"10.0", false},
+	}
+
+	for _, tt := range tests {
+		c := &Case{MinSpecVersion: tt.minSpecVersion}
+		require.Equal(t, tt.expected, c.Supported(), tt.minSpecVersion)
+	}
+}
@@ -0,0 +1,196 @@
+// Package conformance runs test fixtures written in the TOON specification's
+// JSON fixture layout against the encoder and parser. The fixtures shipped
+// in testdata/fixtures/toonify are toonify's own tests, not the official
+// spec suite.
+package conformance
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Palaciodiego008/toonify/encoder"
+	"github.com/Palaciodiego008/toonify/internal/types"
+	"github.com/Palaciodiego008/toonify/parser"
+)
+
+// Fixture categories
+const (
+	CategoryEncode = "encode"
+	CategoryDecode = "decode"
+)
+
+// SupportedSpecVersion is the newest spec version the encoder and parser
+// implement. Cases that need a later version are skipped.
+const SupportedSpecVersion = "3.0"
+
+// Fixture is one fixture file: a group of test cases for a single feature.
+// Version is the spec version of the suite the file comes from, if any.
+type Fixture struct {
+	Version     string `json:"version,omitempty"`
+	Category    string `json:"category"`
+	Description string `json:"description"`
+	Tests       []Case `json:"tests"`
+
+	// File is the path the fixture was loaded from
+	File string `json:"-"`
+}
+
+// Case is a single fixture test case. For encode fixtures Input is a JSON
+// value and Expected a TOON string; decode fixtures are the other way round.
+type Case struct {
+	Name           string          `json:"name"`
+	Input          json.RawMessage `json:"input"`
+	Expected       json.RawMessage `json:"expected"`
+	Options        json.RawMessage `json:"options,omitempty"`
+	ShouldError    bool            `json:"shouldError,omitempty"`
+	SpecSection    string          `json:"specSection,omitempty"`
+	Note           string          `json:"note,omitempty"`
+	MinSpecVersion string          `json:"minSpecVersion,omitempty"`
+}
+
+// Supported reports whether c can run against SupportedSpecVersion
+func (c *Case) Supported() bool {
+	return c.MinSpecVersion == "" || !versionLess(SupportedSpecVersion, c.MinSpecVersion)
+}
+
+// versionLess orders dotted numbers such as spec versions ("2.1") and
+// sections ("13.4") numerically. Parts that are not numbers compare as
+// strings.
+func versionLess(a, b string) bool {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		if aErr != nil || bErr != nil {
+			if as[i] != bs[i] {
+				return as[i] < bs[i]
+			}
+			continue
+		}
+		if an != bn {
+			return an < bn
+		}
+	}
+	return len(as) < len(bs)
+}
+
+// LoadFixtures reads every .json fixture file in dir, ordered by file name
+func LoadFixtures(dir string) ([]*Fixture, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	fixtures := make([]*Fixture, 0, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		fixture := &Fixture{File: file}
+		if err := json.Unmarshal(data, fixture); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		fixtures = append(fixtures, fixture)
+	}
+
+	return fixtures, nil
+}
+
+// Run executes c and returns an error describing how the result differs
+// from the fixture's expectation, or nil if the case passes.
+func (f *Fixture) Run(c *Case) error {
+	switch f.Category {
+	case CategoryEncode:
+		return runEncode(c)
+	case CategoryDecode:
+		return runDecode(c)
+	default:
+		return fmt.Errorf("unknown fixture category %q", f.Category)
+	}
+}
+
+func runEncode(c *Case) error {
+	opts := types.DefaultEncodeOptions()
+	if len(c.Options) > 0 {
+		if err := json.Unmarshal(c.Options, opts); err != nil {
+			return fmt.Errorf("invalid options: %w", err)
+		}
+	}
+
+	input, err := types.UnmarshalOrderedJSON(c.Input)
+	if err != nil {
+		return fmt.Errorf("invalid input: %w", err)
+	}
+
+	result, encodeErr := encoder.New(opts).Encode(input)
+	if c.ShouldError {
+		if encodeErr == nil {
+			return fmt.Errorf("expected an error, got %q", result)
+		}
+		return nil
+	}
+	if encodeErr != nil {
+		return encodeErr
+	}
+
+	var expected string
+	if err := json.Unmarshal(c.Expected, &expected); err != nil {
+		return fmt.Errorf("invalid expected output: %w", err)
+	}
+	if string(result) != expected {
+		return fmt.Errorf("expected:\n%s\ngot:\n%s", expected, result)
+	}
+	return nil
+}
+
+func runDecode(c *Case) error {
+	opts := types.DefaultDecodeOptions()
+	if len(c.Options) > 0 {
+		if err := json.Unmarshal(c.Options, opts); err != nil {
+			return fmt.Errorf("invalid options: %w", err)
+		}
+	}
+
+	var input string
+	if err := json.Unmarshal(c.Input, &input); err != nil {
+		return fmt.Errorf("invalid input: %w", err)
+	}
+
+	value, parseErr := parser.Parse(input, opts)
+	if c.ShouldError {
+		if parseErr == nil {
+			return fmt.Errorf("expected an error, got %v", value)
+		}
+		return nil
+	}
+	if parseErr != nil {
+		return parseErr
+	}
+
+	// Compare through JSON so that int64 and float64 results match the
+	// fixture's numbers
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	var got, expected interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(c.Expected, &expected); err != nil {
+		return fmt.Errorf("invalid expected value: %w", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		return fmt.Errorf("expected %s, got %s", c.Expected, data)
+	}
+	return nil
+}
@@ -1,8 +1,13 @@
 package decoder
 
 import (
+	"bytes"
+	"encoding"
+	"encoding/json"
 	"fmt"
+	"io"
 	"reflect"
+	"sort"
 	"strconv"
 
 	"github.com/Palaciodiego008/toonify/internal/types"
@@ -25,11 +30,38 @@ func New(opts *types.DecodeOptions) *Decoder {
 
 // Decode decodes TOON data into a Go value
 func (d *Decoder) Decode(data []byte, v interface{}) error {
-	parsed, err := parser.Parse(string(data), d.opts)
+	return d.DecodeFrom(bytes.NewReader(data), v)
+}
+
+// DecodeFrom decodes the TOON document read from r into v. Lines are read
+// as they are parsed, and tabular and list arrays that map onto a slice in
+// v are decoded into it one item at a time, so a large array is never held
+// in memory as generic values.
+func (d *Decoder) DecodeFrom(r io.Reader, v interface{}) error {
+	dstValue := reflect.ValueOf(v)
+	if dstValue.Kind() != reflect.Ptr || dstValue.IsNil() {
+		return types.NewToonError("destination must be a pointer", 0, 0)
+	}
+
+	// Expanded paths can only be assigned once the whole object is known
+	var target parser.Target
+	root := &valueTarget{d: d, dst: dstValue.Elem()}
+	if d.opts.ExpandPaths != types.ExpandPathsSafe {
+		target = root
+	}
+
+	// Numbers are parsed as literals, so that destinations that need every
+	// digit get them. Without UseNumber, the others get the int64 or
+	// float64 that the literal would otherwise have been parsed as.
+	parseOpts := *d.opts
+	parseOpts.UseNumber = true
+	parsed, err := parser.ParseReader(r, &parseOpts, target)
 	if err != nil {
 		return err
 	}
-
+	if root.streamed {
+		return nil
+	}
 	return d.assignValue(parsed, v)
 }
 
@@ -46,6 +78,11 @@ func (d *Decoder) assignValue(src interface{}, dst interface{}) error {
 }
 
 func (d *Decoder) assignReflectValue(src, dst reflect.Value) error {
+	// Values read from maps and slices of interface{} are wrapped
+	for src.IsValid() && src.Kind() == reflect.Interface {
+		src = src.Elem()
+	}
+
 	if !src.IsValid() {
 		// Handle nil values
 		if dst.CanSet() {
@@ -54,20 +91,46 @@ func (d *Decoder) assignReflectValue(src, dst reflect.Value) error {
 		return nil
 	}
 
+	if src.Type() == numberType && !d.opts.UseNumber && !d.keepsLiteral(dst.Type()) {
+		src = reflect.ValueOf(plainNumber(types.Number(src.String())))
+	}
+
+	if u, ok := unmarshaler(dst); ok {
+		return d.unmarshal(u, src)
+	}
+	// UnmarshalText comes before the built-in forms, so that a byte slice
+	// type such as net.IP is read from text rather than base64
+	if u, ok := implements(dst, textUnmarshalerType); ok {
+		return d.unmarshalText(u.(encoding.TextUnmarshaler), src)
+	}
+	if ok, err := d.assignNative(src, dst); ok {
+		return err
+	}
+	if d.opts.UseJSONUnmarshaler && dst.Type() != orderedObjectType {
+		if u, ok := implements(dst, jsonUnmarshalerType); ok {
+			return d.unmarshalJSON(u.(json.Unmarshaler), src)
+		}
+	}
+
+	// Ordered objects are assigned to maps and structs like plain maps
+	if obj, ok := src.Interface().(*types.OrderedObject); ok && !isOrderedDestination(dst.Type()) {
+		src = reflect.ValueOf(obj.Values)
+	}
+
 	srcType := src.Type()
 	dstType := dst.Type()
 
 	// Handle interface{} destination
 	if dstType.Kind() == reflect.Interface && dstType.NumMethod() == 0 {
 		if dst.CanSet() {
-			dst.Set(src)
+			dst.Set(d.plainValues(src))
 		}
 		return nil
 	}
 
 	// Handle pointer destination
 	if dstType.Kind() == reflect.Ptr {
-		if src.IsNil() {
+		if src.Kind() == reflect.Ptr && src.IsNil() {
 			if dst.CanSet() {
 				dst.Set(reflect.Zero(dstType))
 			}
@@ -80,10 +143,14 @@ func (d *Decoder) assignReflectValue(src, dst reflect.Value) error {
 		return d.assignReflectValue(src, dst.Elem())
 	}
 
+	if dstType == orderedObjectType {
+		return d.assignOrderedObject(src, dst)
+	}
+
 	// Direct assignment if types match
 	if srcType.AssignableTo(dstType) {
 		if dst.CanSet() {
-			dst.Set(src)
+			dst.Set(d.plainValues(src))
 		}
 		return nil
 	}
@@ -167,6 +234,17 @@ func (d *Decoder) assignInt(src, dst reflect.Value) error {
 	case reflect.Float32, reflect.Float64:
 		i = int64(src.Float())
 	case reflect.String:
+		if src.Type() == numberType {
+			n, err := numberInt(types.Number(src.String()))
+			if err != nil {
+				return err
+			}
+			if !n.IsInt64() || dst.OverflowInt(n.Int64()) {
+				return types.NewToonError(fmt.Sprintf("number %s overflows %v", src.String(), dst.Type()), 0, 0)
+			}
+			i = n.Int64()
+			break
+		}
 		var err error
 		i, err = strconv.ParseInt(src.String(), 10, 64)
 		if err != nil {
@@ -192,6 +270,17 @@ func (d *Decoder) assignUint(src, dst reflect.Value) error {
 	case reflect.Float32, reflect.Float64:
 		u = uint64(src.Float())
 	case reflect.String:
+		if src.Type() == numberType {
+			n, err := numberInt(types.Number(src.String()))
+			if err != nil {
+				return err
+			}
+			if !n.IsUint64() || dst.OverflowUint(n.Uint64()) {
+				return types.NewToonError(fmt.Sprintf("number %s overflows %v", src.String(), dst.Type()), 0, 0)
+			}
+			u = n.Uint64()
+			break
+		}
 		var err error
 		u, err = strconv.ParseUint(src.String(), 10, 64)
 		if err != nil {
@@ -222,6 +311,14 @@ func (d *Decoder) assignFloat(src, dst reflect.Value) error {
 		if err != nil {
 			return types.NewToonError(fmt.Sprintf("cannot parse float from string: %s", src.String()), 0, 0)
 		}
+		if src.Type() == numberType {
+			if dst.OverflowFloat(f) {
+				return types.NewToonError(fmt.Sprintf("number %s overflows %v", src.String(), dst.Type()), 0, 0)
+			}
+			if f == 0 {
+				f = 0 // normalise -0, as the parser does
+			}
+		}
 	default:
 		return types.NewToonError(fmt.Sprintf("cannot convert %v to float", src.Type()), 0, 0)
 	}
@@ -329,21 +426,103 @@ func (d *Decoder) assignStruct(src, dst reflect.Value) error {
 		// Find struct field
 		field, found := utils.FindStructField(dstType, keyStr)
 		if !found {
+			if inline, ok := utils.InlineMapField(dstType); ok {
+				if err := d.assignInlineEntry(dst, inline, keyStr, srcValue); err != nil {
+					return err
+				}
+				continue
+			}
 			if d.opts.Strict {
 				return types.NewToonError(fmt.Sprintf("unknown field: %s", keyStr), 0, 0)
 			}
 			continue
 		}
 
-		dstField := dst.Field(field.Index[0])
-		if !dstField.CanSet() {
+		dstField, ok := structField(dst, field.Index)
+		if !ok {
 			continue
 		}
 
-		if err := d.assignReflectValue(srcValue, dstField); err != nil {
+		if err := d.assignField(srcValue, dstField, field.Tag); err != nil {
 			return err
 		}
 	}
 
 	return nil
 }
+
+// assignInlineEntry stores a key that matches no field of dst in its
+// inline map
+func (d *Decoder) assignInlineEntry(dst reflect.Value, inline utils.StructField, key string, src reflect.Value) error {
+	m, ok := structField(dst, inline.Index)
+	if !ok {
+		return nil
+	}
+	if m.IsNil() {
+		m.Set(reflect.MakeMap(m.Type()))
+	}
+
+	elem := reflect.New(m.Type().Elem()).Elem()
+	if err := d.assignReflectValue(src, elem); err != nil {
+		return err
+	}
+	m.SetMapIndex(reflect.ValueOf(key).Convert(m.Type().Key()), elem)
+	return nil
+}
+
+// structField returns the field of dst at index, allocating nil embedded
+// pointers on the way. It reports false if the field cannot be set.
+func structField(dst reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && dst.Kind() == reflect.Ptr {
+			if dst.IsNil() {
+				if !dst.CanSet() {
+					return reflect.Value{}, false
+				}
+				dst.Set(reflect.New(dst.Type().Elem()))
+			}
+			dst = dst.Elem()
+		}
+		dst = dst.Field(x)
+	}
+	return dst, dst.CanSet()
+}
+
+var orderedObjectType = reflect.TypeOf(types.OrderedObject{})
+
+// isOrderedDestination reports whether t can hold an *OrderedObject as is.
+func isOrderedDestination(t reflect.Type) bool {
+	return (t.Kind() == reflect.Interface && t.NumMethod() == 0) ||
+		t == orderedObjectType || t == reflect.PtrTo(orderedObjectType)
+}
+
+// assignOrderedObject fills an OrderedObject destination. Plain maps carry
+// no order, so their keys are sorted.
+func (d *Decoder) assignOrderedObject(src, dst reflect.Value) error {
+	src = d.plainValues(src)
+	if obj, ok := src.Interface().(*types.OrderedObject); ok {
+		if dst.CanSet() {
+			dst.Set(reflect.ValueOf(*obj))
+		}
+		return nil
+	}
+
+	if src.Kind() != reflect.Map || src.Type().Key().Kind() != reflect.String {
+		return types.NewToonError(fmt.Sprintf("cannot assign %v to OrderedObject", src.Type()), 0, 0)
+	}
+
+	keys := make([]string, 0, src.Len())
+	for _, key := range src.MapKeys() {
+		keys = append(keys, key.String())
+	}
+	sort.Strings(keys)
+
+	obj := types.NewOrderedObject()
+	for _, key := range keys {
+		obj.Set(key, src.MapIndex(reflect.ValueOf(key)).Interface())
+	}
+	if dst.CanSet() {
+		dst.Set(reflect.ValueOf(*obj))
+	}
+	return nil
+}
@@ -1,7 +1,15 @@
 package decoder
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/netip"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Palaciodiego008/toonify/internal/types"
 	"github.com/stretchr/testify/assert"
@@ -48,6 +56,29 @@ age: 30`
 	assert.Equal(t, int64(30), result["age"])
 }
 
+func TestDecodeEmptyDocument(t *testing.T) {
+	dec := New(types.DefaultDecodeOptions())
+
+	// An empty or blank document is an empty object
+	for _, input := range []string{"", "\n\n", "  \n"} {
+		var result interface{}
+		require.NoError(t, dec.Decode([]byte(input), &result), "%q", input)
+		assert.Equal(t, map[string]interface{}{}, result, "%q", input)
+
+		var value struct {
+			A int `json:"a"`
+		}
+		require.NoError(t, dec.DecodeFrom(strings.NewReader(input), &value), "%q", input)
+		assert.Equal(t, 0, value.A)
+	}
+
+	opts := types.DefaultDecodeOptions()
+	opts.PreserveOrder = true
+	var ordered interface{}
+	require.NoError(t, New(opts).Decode(nil, &ordered))
+	assert.Empty(t, ordered.(*types.OrderedObject).Keys)
+}
+
 func TestDecodeNestedObject(t *testing.T) {
 	dec := New(types.DefaultDecodeOptions())
 
@@ -63,3 +94,910 @@ func TestDecodeNestedObject(t *testing.T) {
 	assert.Equal(t, "Alice", user["name"])
 	assert.Equal(t, int64(30), user["age"])
 }
+
+func TestDecodeTabularArray(t *testing.T) {
+	dec := New(types.DefaultDecodeOptions())
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"spec", "users[2]{id,name}:\n  1,Alice\n  2,Bob"},
+		{"legacy", "users:\n  [2]{id,name}:\n    1,Alice\n    2,Bob"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result map[string]interface{}
+			err := dec.Decode([]byte(tt.input), &result)
+			require.NoError(t, err)
+
+			expected := []interface{}{
+				map[string]interface{}{"id": int64(1), "name": "Alice"},
+				map[string]interface{}{"id": int64(2), "name": "Bob"},
+			}
+			assert.Equal(t, expected, result["users"])
+		})
+	}
+}
+
+func TestDecodeInlineArray(t *testing.T) {
+	dec := New(types.DefaultDecodeOptions())
+
+	var result map[string]interface{}
+	err := dec.Decode([]byte("tags[4]: a,\"b,c\",\"42\",42\nempty[0]:"), &result)
+	require.NoError(t, err)
+
+	assert.Equal(t, []interface{}{"a", "b,c", "42", int64(42)}, result["tags"])
+	assert.Equal(t, []interface{}{}, result["empty"])
+}
+
+func TestDecodeDelimiters(t *testing.T) {
+	dec := New(types.DefaultDecodeOptions())
+
+	input := "tags[2|]: a,b|c\nrows[2\t]{id\tname}:\n  1\tx,y\n  2\tz|w"
+
+	var result map[string]interface{}
+	err := dec.Decode([]byte(input), &result)
+	require.NoError(t, err)
+
+	assert.Equal(t, []interface{}{"a,b", "c"}, result["tags"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"id": int64(1), "name": "x,y"},
+		map[string]interface{}{"id": int64(2), "name": "z|w"},
+	}, result["rows"])
+}
+
+func TestDecodeExpandPaths(t *testing.T) {
+	opts := types.DefaultDecodeOptions()
+	opts.ExpandPaths = types.ExpandPathsSafe
+	dec := New(opts)
+
+	input := "server.http.port: 8080\nserver:\n  host: localhost"
+
+	var result map[string]interface{}
+	err := dec.Decode([]byte(input), &result)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"server": map[string]interface{}{
+			"host": "localhost",
+			"http": map[string]interface{}{"port": int64(8080)},
+		},
+	}, result)
+}
+
+func TestDecodeExpandPathsConflict(t *testing.T) {
+	opts := types.DefaultDecodeOptions()
+	opts.ExpandPaths = types.ExpandPathsSafe
+	input := []byte("a.b: 1\na: 2")
+
+	var result map[string]interface{}
+	err := New(opts).Decode(input, &result)
+	require.Error(t, err)
+
+	var toonErr *types.ToonError
+	require.ErrorAs(t, err, &toonErr)
+	assert.Equal(t, 2, toonErr.Line)
+	assert.Equal(t, 1, toonErr.Column)
+
+	opts.Strict = false
+	result = nil
+	require.NoError(t, New(opts).Decode(input, &result))
+	assert.Equal(t, int64(2), result["a"])
+}
+
+func TestDecodeEscapes(t *testing.T) {
+	dec := New(types.DefaultDecodeOptions())
+
+	var result map[string]interface{}
+	err := dec.Decode([]byte(`text: "a\"b\\c\nd\re\tf"`), &result)
+	require.NoError(t, err)
+	assert.Equal(t, "a\"b\\c\nd\re\tf", result["text"])
+}
+
+func TestDecodeInvalidEscape(t *testing.T) {
+	input := []byte(`text: "a\qb"`)
+
+	var result map[string]interface{}
+	err := New(types.DefaultDecodeOptions()).Decode(input, &result)
+	var toonErr *types.ToonError
+	require.ErrorAs(t, err, &toonErr)
+	assert.Equal(t, 1, toonErr.Line)
+	assert.Equal(t, 9, toonErr.Column)
+
+	opts := types.DefaultDecodeOptions()
+	opts.Strict = false
+	require.NoError(t, New(opts).Decode(input, &result))
+	assert.Equal(t, `a\qb`, result["text"])
+}
+
+func TestDecodeQuotedKeys(t *testing.T) {
+	dec := New(types.DefaultDecodeOptions())
+
+	input := `"a:b": 1
+"my key":
+  "": 2
+"my tags"[2]: x,y
+rows[1]{"cpu %","a,b"}:
+  3,4`
+
+	var result map[string]interface{}
+	err := dec.Decode([]byte(input), &result)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"a:b":     int64(1),
+		"my key":  map[string]interface{}{"": int64(2)},
+		"my tags": []interface{}{"x", "y"},
+		"rows": []interface{}{
+			map[string]interface{}{"cpu %": int64(3), "a,b": int64(4)},
+		},
+	}, result)
+}
+
+func TestDecodeQuotedKeysAreNotExpanded(t *testing.T) {
+	opts := types.DefaultDecodeOptions()
+	opts.ExpandPaths = types.ExpandPathsSafe
+
+	var result map[string]interface{}
+	err := New(opts).Decode([]byte("\"a.b\": 1\nc.d: 2"), &result)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"a.b": int64(1),
+		"c":   map[string]interface{}{"d": int64(2)},
+	}, result)
+}
+
+func TestDecodeStruct(t *testing.T) {
+	type user struct {
+		Name string   `json:"name"`
+		Age  int      `json:"age"`
+		Tags []string `json:"tags"`
+	}
+
+	var result user
+	err := New(types.DefaultDecodeOptions()).Decode([]byte("name: Alice\nage: 30\ntags[2]: a,b"), &result)
+	require.NoError(t, err)
+	assert.Equal(t, user{Name: "Alice", Age: 30, Tags: []string{"a", "b"}}, result)
+}
+
+func TestDecodePreserveOrder(t *testing.T) {
+	opts := types.DefaultDecodeOptions()
+	opts.PreserveOrder = true
+
+	var result interface{}
+	err := New(opts).Decode([]byte("zeta: 1\nalpha:\n  y: 2\n  x: 3\nrows[1]{b,a}:\n  4,5"), &result)
+	require.NoError(t, err)
+
+	obj, ok := result.(*types.OrderedObject)
+	require.True(t, ok)
+	assert.Equal(t, []string{"zeta", "alpha", "rows"}, obj.Keys)
+	assert.Equal(t, []string{"y", "x"}, obj.Values["alpha"].(*types.OrderedObject).Keys)
+
+	rows := obj.Values["rows"].([]interface{})
+	assert.Equal(t, []string{"b", "a"}, rows[0].(*types.OrderedObject).Keys)
+
+	var ordered types.OrderedObject
+	require.NoError(t, New(opts).Decode([]byte("b: 1\na: 2"), &ordered))
+	assert.Equal(t, []string{"b", "a"}, ordered.Keys)
+}
+
+func TestDecodeNumbers(t *testing.T) {
+	dec := New(types.DefaultDecodeOptions())
+
+	input := "a: 1e3\nb: -0\nc: 1.50\nd: 2E-2\ne: 05\nf: NaN\ng: 1_000"
+
+	var result map[string]interface{}
+	err := dec.Decode([]byte(input), &result)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"a": 1000.0,
+		"b": int64(0),
+		"c": 1.5,
+		"d": 0.02,
+		"e": "05",
+		"f": "NaN",
+		"g": "1_000",
+	}, result)
+}
+
+func TestDecodeUseNumber(t *testing.T) {
+	opts := types.DefaultDecodeOptions()
+	opts.UseNumber = true
+	input := "id: 18446744073709551616\nprice: 0.1000000000000000055511151231257827\nsmall: 1e3\nname: \"12\"\nnums[2]: 1,-0"
+
+	var generic map[string]interface{}
+	require.NoError(t, New(opts).Decode([]byte(input), &generic))
+	assert.Equal(t, map[string]interface{}{
+		"id":    types.Number("18446744073709551616"),
+		"price": types.Number("0.1000000000000000055511151231257827"),
+		"small": types.Number("1e3"),
+		"name":  "12",
+		"nums":  []interface{}{types.Number("1"), types.Number("-0")},
+	}, generic)
+
+	type exact struct {
+		ID    *big.Int    `json:"id"`
+		Price *big.Rat    `json:"price"`
+		Small json.Number `json:"small"`
+		Name  string      `json:"name"`
+		Nums  []float64   `json:"nums"`
+	}
+	var result exact
+	require.NoError(t, New(opts).Decode([]byte(input), &result))
+	assert.Equal(t, "18446744073709551616", result.ID.String())
+	assert.Equal(t, "0.1000000000000000055511151231257827", result.Price.FloatString(34))
+	assert.Equal(t, json.Number("1e3"), result.Small)
+	assert.Equal(t, "12", result.Name)
+	assert.Equal(t, []float64{1, 0}, result.Nums)
+
+	var value struct {
+		Count int       `json:"count"`
+		Ratio big.Float `json:"ratio"`
+	}
+	require.NoError(t, New(opts).Decode([]byte("count: 3\nratio: 2.5"), &value))
+	assert.Equal(t, 3, value.Count)
+	assert.Equal(t, "2.5", value.Ratio.Text('f', -1))
+	assert.Error(t, New(opts).Decode([]byte("count: 99999999999999999999"), &value))
+}
+
+func TestDecodeExactNumbers(t *testing.T) {
+	type exact struct {
+		ID    *big.Int      `json:"id"`
+		Big   types.Number  `json:"big"`
+		Ratio json.Number   `json:"ratio"`
+		Count int           `json:"count"`
+		Size  uint8         `json:"size"`
+		Wait  time.Duration `json:"wait"`
+		Any   interface{}   `json:"any"`
+	}
+	input := "id: 18446744073709551616\nbig: 1e+20\nratio: 1.5\ncount: 1e3\nsize: 2.0\nwait: 1500\nany: 7"
+
+	var result exact
+	require.NoError(t, New(types.DefaultDecodeOptions()).Decode([]byte(input), &result))
+	assert.Equal(t, "18446744073709551616", result.ID.String())
+	assert.Equal(t, types.Number("1e+20"), result.Big)
+	assert.Equal(t, json.Number("1.5"), result.Ratio)
+	assert.Equal(t, 1000, result.Count)
+	assert.Equal(t, uint8(2), result.Size)
+	assert.Equal(t, 1500*time.Nanosecond, result.Wait)
+	assert.Equal(t, int64(7), result.Any)
+
+	opts := types.DefaultDecodeOptions()
+	opts.UseNumber = true
+	var literal exact
+	require.NoError(t, New(opts).Decode([]byte(input), &literal))
+	assert.Equal(t, 1000, literal.Count)
+	assert.Equal(t, 1500*time.Nanosecond, literal.Wait)
+	assert.Equal(t, types.Number("7"), literal.Any)
+
+	// Numeric fields check the literal whether or not UseNumber is set
+	for _, bad := range []string{"count: 1.5", "count: 2.5", "count: 1e400", "size: -1", "size: 256", "wait: 1e-3"} {
+		for _, o := range []*types.DecodeOptions{types.DefaultDecodeOptions(), opts} {
+			var value exact
+			assert.Error(t, New(o).Decode([]byte(bad), &value), bad)
+		}
+	}
+
+	var limits struct {
+		Max      uint64  `json:"max"`
+		Min      int64   `json:"min"`
+		Overflow int64   `json:"overflow"`
+		Small    float32 `json:"small"`
+	}
+	dec := New(types.DefaultDecodeOptions())
+	require.NoError(t, dec.Decode([]byte("max: 18446744073709551615\nmin: -9223372036854775808"), &limits))
+	assert.Equal(t, uint64(18446744073709551615), limits.Max)
+	assert.Equal(t, int64(-9223372036854775808), limits.Min)
+	assert.Error(t, dec.Decode([]byte("max: 18446744073709551616"), &limits))
+	assert.Error(t, dec.Decode([]byte("overflow: 9223372036854775808"), &limits))
+	assert.Error(t, dec.Decode([]byte("small: 1e39"), &limits))
+}
+
+func TestDecodeListItemObjects(t *testing.T) {
+	dec := New(types.DefaultDecodeOptions())
+
+	tests := []struct {
+		name     string
+		input    string
+		expected []interface{}
+	}{
+		{
+			"fields",
+			"items[2]:\n  - id: 1\n    name: Alice\n  - id: 2",
+			[]interface{}{
+				map[string]interface{}{"id": int64(1), "name": "Alice"},
+				map[string]interface{}{"id": int64(2)},
+			},
+		},
+		{
+			"nested_object",
+			"items[1]:\n  - a:\n      x: 1\n    b: 2",
+			[]interface{}{
+				map[string]interface{}{"a": map[string]interface{}{"x": int64(1)}, "b": int64(2)},
+			},
+		},
+		{
+			"tabular_array",
+			"items[1]:\n  - a[2]{x}:\n      1\n      2\n    b: 2",
+			[]interface{}{
+				map[string]interface{}{"a": []interface{}{
+					map[string]interface{}{"x": int64(1)},
+					map[string]interface{}{"x": int64(2)},
+				}, "b": int64(2)},
+			},
+		},
+		{
+			"mixed",
+			"items[3]:\n  - a[2]: 1,2\n  - \"k: v\"\n  - x",
+			[]interface{}{
+				map[string]interface{}{"a": []interface{}{int64(1), int64(2)}},
+				"k: v",
+				"x",
+			},
+		},
+		{
+			"empty",
+			"items[2]:\n  -\n  - 1",
+			[]interface{}{map[string]interface{}{}, int64(1)},
+		},
+		{
+			"legacy",
+			"items:\n  -\n    id: 1\n    name: Alice",
+			[]interface{}{map[string]interface{}{"id": int64(1), "name": "Alice"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result map[string]interface{}
+			err := dec.Decode([]byte(tt.input), &result)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result["items"])
+		})
+	}
+}
+
+func TestDecodeRootAndNestedArrays(t *testing.T) {
+	dec := New(types.DefaultDecodeOptions())
+
+	tests := []struct {
+		name     string
+		input    string
+		expected interface{}
+	}{
+		{"root_inline", "[3]: 1,2,3", []interface{}{int64(1), int64(2), int64(3)}},
+		{"root_empty", "[0]:", []interface{}{}},
+		{"root_pipe", "[2|]: a,b|c", []interface{}{"a,b", "c"}},
+		{
+			"root_tabular",
+			"[2]{a}:\n  1\n  2",
+			[]interface{}{map[string]interface{}{"a": int64(1)}, map[string]interface{}{"a": int64(2)}},
+		},
+		{
+			"arrays_of_arrays",
+			"[2]:\n  - [2]: 1,2.5\n  - [0]:",
+			[]interface{}{[]interface{}{int64(1), 2.5}, []interface{}{}},
+		},
+		{
+			"nested_complex",
+			"[2]:\n  - [2]:\n    - [1]: 1\n    - a: 1\n  - [1]{a}:\n    1",
+			[]interface{}{
+				[]interface{}{[]interface{}{int64(1)}, map[string]interface{}{"a": int64(1)}},
+				[]interface{}{map[string]interface{}{"a": int64(1)}},
+			},
+		},
+		{"primitive_item", "[1]:\n  - \"[1]: x\"", []interface{}{"[1]: x"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result interface{}
+			err := dec.Decode([]byte(tt.input), &result)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestDecodeContentAfterRoot(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		line   int
+		column int
+	}{
+		{"inline_array", "[2]: 1,2\nb: 1", 2, 1},
+		{"tabular_array", "[1]{id}:\n  1\nb: 1", 3, 1},
+		{"primitive", "hello\n\nworld", 3, 1},
+	}
+
+	lax := types.DefaultDecodeOptions()
+	lax.Strict = false
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, opts := range []*types.DecodeOptions{types.DefaultDecodeOptions(), lax} {
+				var result interface{}
+				err := New(opts).Decode([]byte(tt.input), &result)
+				var toonErr *types.ToonError
+				require.ErrorAs(t, err, &toonErr)
+				assert.Equal(t, tt.line, toonErr.Line)
+				assert.Equal(t, tt.column, toonErr.Column)
+
+				var rows []map[string]interface{}
+				assert.Error(t, New(opts).DecodeFrom(strings.NewReader(tt.input), &rows))
+			}
+		})
+	}
+}
+
+func TestDecodeStrictValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		line    int
+		column  int
+		lenient bool // accepted when Strict is off
+	}{
+		{"too_few_rows", "rows[3]{a,b}:\n  1,2\n  3,4", 1, 5, true},
+		{"too_many_rows", "rows[1]{a,b}:\n  1,2\n  3,4", 1, 5, true},
+		{"inline_length", "tags[3]: a,b", 1, 5, true},
+		{"list_length", "items[1]:\n  - a\n  - b", 1, 6, true},
+		{"indent_multiple", "a:\n   b: 2", 2, 4, false},
+		{"tab_indent", "a:\n\tb: 2", 2, 1, true},
+		{"blank_line_in_rows", "rows[2]{a}:\n  1\n\n  2", 3, 1, true},
+		{"blank_line_in_list", "items[2]:\n  - a: 1\n\n    b: 2\n  - x", 3, 1, true},
+		{"duplicate_key", "\na: 1\nb: 2\na: 3", 4, 1, true},
+		{"duplicate_nested_key", "obj:\n  a: 1\n  a: 2", 3, 3, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result interface{}
+			err := New(types.DefaultDecodeOptions()).Decode([]byte(tt.input), &result)
+			var toonErr *types.ToonError
+			require.ErrorAs(t, err, &toonErr)
+			assert.Equal(t, tt.line, toonErr.Line)
+			assert.Equal(t, tt.column, toonErr.Column)
+
+			if !tt.lenient {
+				return
+			}
+			opts := types.DefaultDecodeOptions()
+			opts.Strict = false
+			assert.NoError(t, New(opts).Decode([]byte(tt.input), &result))
+		})
+	}
+}
+
+func TestDecodeHugeDeclaredLength(t *testing.T) {
+	type tagged struct {
+		Tags []string
+		Rows []map[string]int
+	}
+	inputs := []string{
+		"Tags[999999999999999]:",
+		"Tags[999999999999999]: a,b",
+		"Tags[999999999999999]:\n  - a",
+		"Rows[999999999999999]{id}:\n  1",
+	}
+
+	// The declared length is not trusted for allocation, so these fail the
+	// length check or come back short instead of panicking
+	for _, input := range inputs {
+		assert.Error(t, New(nil).Decode([]byte(input), &tagged{}), input)
+
+		var generic interface{}
+		assert.Error(t, New(nil).Decode([]byte(input), &generic), input)
+	}
+
+	opts := types.DefaultDecodeOptions()
+	opts.Strict = false
+	var result tagged
+	require.NoError(t, New(opts).Decode([]byte(inputs[2]), &result))
+	assert.Equal(t, []string{"a"}, result.Tags)
+}
+
+func TestDecodeBlankLineAfterArray(t *testing.T) {
+	var result map[string]interface{}
+	err := New(types.DefaultDecodeOptions()).Decode([]byte("items[1]:\n  - a\n\nnext: 1"), &result)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"items": []interface{}{"a"}, "next": int64(1)}, result)
+}
+
+func TestDecodeLegacySpec(t *testing.T) {
+	opts := types.DefaultDecodeOptions()
+	opts.SpecVersion = types.SpecVersionLegacy
+	dec := New(opts)
+
+	// Output of toonify 1.0, which doubled nested indentation
+	tests := []struct {
+		name     string
+		input    string
+		expected interface{}
+	}{
+		{
+			"nested_tabular",
+			"a:\n    b:\n        rows:\n      [2]{x}:\n            1\n            2",
+			map[string]interface{}{"a": map[string]interface{}{"b": map[string]interface{}{
+				"rows": []interface{}{map[string]interface{}{"x": int64(1)}, map[string]interface{}{"x": int64(2)}},
+			}}},
+		},
+		{
+			"tab_rows_with_null_cells",
+			"rows:\n  [2]{b,a}:\n    \t1\n    x y\t2",
+			map[string]interface{}{"rows": []interface{}{
+				map[string]interface{}{"a": int64(1), "b": nil},
+				map[string]interface{}{"a": int64(2), "b": "x y"},
+			}},
+		},
+		{
+			"list_items",
+			"items:\n    -\n        a: 1\n        t:\n            - 1\n    - 2\n    -\n        - 3\n        - 4",
+			map[string]interface{}{"items": []interface{}{
+				map[string]interface{}{"a": int64(1), "t": []interface{}{int64(1)}},
+				int64(2),
+				[]interface{}{int64(3), int64(4)},
+			}},
+		},
+		{
+			"empty_values",
+			"o:\n  {}\nx: 1\ne:\n  []",
+			map[string]interface{}{"e": []interface{}{}, "o": map[string]interface{}{}, "x": int64(1)},
+		},
+		{
+			"root_tabular",
+			"[2]{a,b}:\n1,2\n3,4",
+			[]interface{}{
+				map[string]interface{}{"a": int64(1), "b": int64(2)},
+				map[string]interface{}{"a": int64(3), "b": int64(4)},
+			},
+		},
+		{"root_list", "- 1\n- 2", []interface{}{int64(1), int64(2)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result interface{}
+			err := dec.Decode([]byte(tt.input), &result)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestDecodeUnsupportedSpecVersion(t *testing.T) {
+	opts := types.DefaultDecodeOptions()
+	opts.SpecVersion = "1.5"
+
+	var result interface{}
+	assert.Error(t, New(opts).Decode([]byte("a: 1"), &result))
+}
+
+// rowReader produces a tabular document of n rows as it is read, so that
+// the full text never exists in memory.
+type rowReader struct {
+	n, next int
+	pending string
+}
+
+func (r *rowReader) Read(p []byte) (int, error) {
+	if r.pending == "" {
+		switch {
+		case r.next == 0:
+			r.pending = fmt.Sprintf("users[%d]{id,name}:\n", r.n)
+		case r.next <= r.n:
+			r.pending = fmt.Sprintf("  %d,user%d\n", r.next, r.next)
+		case r.next == r.n+1:
+			r.pending = "count: " + fmt.Sprint(r.n) + "\n"
+		default:
+			return 0, io.EOF
+		}
+		r.next++
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+func TestDecodeFrom(t *testing.T) {
+	type user struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+
+	t.Run("field", func(t *testing.T) {
+		var result struct {
+			Users []user `json:"users"`
+			Count int    `json:"count"`
+		}
+		require.NoError(t, New(nil).DecodeFrom(&rowReader{n: 100000}, &result))
+		require.Len(t, result.Users, 100000)
+		assert.Equal(t, user{ID: 100000, Name: "user100000"}, result.Users[99999])
+		assert.Equal(t, 100000, result.Count)
+	})
+
+	t.Run("root", func(t *testing.T) {
+		var result []*user
+		input := "[2]{id,name}:\n  1,Alice\n  2,Bob"
+		require.NoError(t, New(nil).DecodeFrom(strings.NewReader(input), &result))
+		assert.Equal(t, []*user{{1, "Alice"}, {2, "Bob"}}, result)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		var result struct {
+			Users []user `json:"users"`
+		}
+		input := "users[2]:\n  - id: 1\n    name: Alice\n  - id: 2\n    name: Bob"
+		require.NoError(t, New(nil).DecodeFrom(strings.NewReader(input), &result))
+		assert.Equal(t, []user{{1, "Alice"}, {2, "Bob"}}, result.Users)
+	})
+
+	t.Run("bad row", func(t *testing.T) {
+		var result []user
+		input := "[2]{id,name}:\n  1,Alice\n  x,Bob"
+		err := New(nil).DecodeFrom(strings.NewReader(input), &result)
+		var toonErr *types.ToonError
+		require.ErrorAs(t, err, &toonErr)
+		assert.Equal(t, 3, toonErr.Line)
+	})
+
+	t.Run("strict length", func(t *testing.T) {
+		var result []user
+		input := "[3]{id,name}:\n  1,Alice\n  2,Bob"
+		assert.Error(t, New(nil).DecodeFrom(strings.NewReader(input), &result))
+	})
+}
+
+type money struct {
+	cents    int64
+	currency string
+}
+
+func (m *money) UnmarshalTOON(data []byte) error {
+	var units, cents int64
+	if _, err := fmt.Sscanf(string(data), "%d.%d %s", &units, &cents, &m.currency); err != nil {
+		return err
+	}
+	m.cents = units*100 + cents
+	return nil
+}
+
+// labels keeps the TOON text it was decoded from
+type labels []string
+
+func (l *labels) UnmarshalTOON(data []byte) error {
+	*l = labels{string(data)}
+	return nil
+}
+
+func TestDecodeUnmarshaler(t *testing.T) {
+	type row struct {
+		ID    int    `json:"id"`
+		Total money  `json:"total"`
+		Tip   *money `json:"tip"`
+	}
+
+	var result struct {
+		Rows   []row  `json:"rows"`
+		Labels labels `json:"labels"`
+	}
+	input := "rows[2]{id,total,tip}:\n  1,1.00 USD,0.10 USD\n  2,2.50 EUR,null\nlabels[2]:\n  - a\n  - b"
+
+	for _, decode := range []func() error{
+		func() error { return New(nil).Decode([]byte(input), &result) },
+		func() error { return New(nil).DecodeFrom(strings.NewReader(input), &result) },
+	} {
+		result.Rows, result.Labels = nil, nil
+		require.NoError(t, decode())
+		assert.Equal(t, []row{
+			{1, money{100, "USD"}, &money{10, "USD"}},
+			{2, money{250, "EUR"}, nil},
+		}, result.Rows)
+		assert.Equal(t, labels{"[2]: a,b"}, result.Labels)
+	}
+
+	var single money
+	require.NoError(t, New(nil).Decode([]byte("12.50 USD"), &single))
+	assert.Equal(t, money{1250, "USD"}, single)
+
+	assert.Error(t, New(nil).Decode([]byte("total: free"), &struct {
+		Total money `json:"total"`
+	}{}))
+}
+
+type level int
+
+func (l *level) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "low":
+		*l = 0
+	case "high":
+		*l = 1
+	default:
+		return fmt.Errorf("unknown level %q", text)
+	}
+	return nil
+}
+
+// code keeps the text it was decoded from
+type code string
+
+func (c *code) UnmarshalText(text []byte) error {
+	*c = code("#" + string(text))
+	return nil
+}
+
+// legacyJSON only knows how to unmarshal itself from JSON
+type legacyJSON struct{ raw string }
+
+func (l *legacyJSON) UnmarshalJSON(data []byte) error {
+	l.raw = string(data)
+	return nil
+}
+
+func TestDecodeTextUnmarshaler(t *testing.T) {
+	type row struct {
+		Level level      `json:"level"`
+		Host  netip.Addr `json:"host"`
+		Code  code       `json:"code"`
+	}
+
+	var rows []row
+	input := "[2]{level,host,code}:\n  low,\"::1\",7\n  high,10.0.0.1,x"
+	require.NoError(t, New(nil).Decode([]byte(input), &rows))
+	assert.Equal(t, []row{
+		{0, netip.MustParseAddr("::1"), "#7"},
+		{1, netip.MustParseAddr("10.0.0.1"), "#x"},
+	}, rows)
+
+	// net.IP is a byte slice, but it is read as text rather than base64
+	var addr struct{ IP net.IP }
+	require.NoError(t, New(nil).Decode([]byte("IP: 1.2.3.4"), &addr))
+	assert.Equal(t, "1.2.3.4", addr.IP.String())
+
+	var keyed map[level]code
+	require.NoError(t, New(nil).Decode([]byte("high: a\nlow: b"), &keyed))
+	assert.Equal(t, map[level]code{1: "#a", 0: "#b"}, keyed)
+
+	var single row
+	assert.ErrorContains(t, New(nil).Decode([]byte("level: medium"), &single), `unknown level "medium"`)
+	assert.Error(t, New(nil).Decode([]byte("code:\n  a: 1"), &single))
+}
+
+func TestDecodeJSONUnmarshaler(t *testing.T) {
+	type wrapper struct {
+		Legacy legacyJSON `json:"legacy"`
+	}
+	input := "legacy:\n  z: 7\n  a[2]: 1,x"
+
+	// Without the option the type is decoded by reflection, and has no
+	// exported fields
+	assert.Error(t, New(nil).Decode([]byte(input), &wrapper{}))
+
+	opts := types.DefaultDecodeOptions()
+	opts.UseJSONUnmarshaler = true
+	opts.PreserveOrder = true
+	var result wrapper
+	require.NoError(t, New(opts).Decode([]byte(input), &result))
+	assert.Equal(t, `{"z":7,"a":[1,"x"]}`, result.Legacy.raw)
+
+	var items []legacyJSON
+	require.NoError(t, New(opts).Decode([]byte("[2]: 1,x"), &items))
+	assert.Equal(t, []legacyJSON{{"1"}, {`"x"`}}, items)
+}
+
+func TestDecodeStructTags(t *testing.T) {
+	type tagged struct {
+		Name   string `json:"name" toon:"title"`
+		Secret string `json:"-"`
+		Count  int    `json:"count,string"`
+		Ratio  float64
+		Flag   bool `json:"flag,string"`
+		Exact  int  `json:"exact"`
+		Folded int  `json:"EXACT"`
+	}
+
+	var result tagged
+	input := "title: a\ncount: \"42\"\nratio: 0.5\nflag: \"true\"\nexact: 1\nEXACT: 2"
+	require.NoError(t, New(nil).Decode([]byte(input), &result))
+	assert.Equal(t, tagged{Name: "a", Count: 42, Ratio: 0.5, Flag: true, Exact: 1, Folded: 2}, result)
+
+	// The json name is overridden by the toon tag, and "-" fields are unknown
+	for _, input := range []string{"name: a", "Secret: s"} {
+		assert.Error(t, New(nil).Decode([]byte(input), &tagged{}), input)
+	}
+}
+
+type Timestamps struct {
+	Created string `json:"created"`
+	Updated string `json:"updated"`
+}
+
+type owner struct {
+	Owner string `json:"owner"`
+}
+
+type record struct {
+	*Timestamps
+	owner
+	ID     int               `json:"id"`
+	Extra  map[string]string `toon:",inline"`
+	Paging struct {
+		Page int `json:"page"`
+	} `toon:",inline"`
+}
+
+func TestDecodeEmbeddedStructs(t *testing.T) {
+	input := "id: 1\ncreated: mon\nowner: ana\npage: 2\nzone: eu\nregion: west"
+	expected := record{
+		Timestamps: &Timestamps{Created: "mon"},
+		owner:      owner{Owner: "ana"},
+		ID:         1,
+		Extra:      map[string]string{"zone": "eu", "region": "west"},
+	}
+	expected.Paging.Page = 2
+
+	// The nil embedded pointer is allocated, and keys that match no field
+	// go to the inline map instead of failing in strict mode
+	var result record
+	require.NoError(t, New(nil).Decode([]byte(input), &result))
+	assert.Equal(t, expected, result)
+
+	var streamed record
+	require.NoError(t, New(nil).DecodeFrom(strings.NewReader(input), &streamed))
+	assert.Equal(t, expected, streamed)
+
+	// Without an inline map, unknown keys are still rejected
+	assert.Error(t, New(nil).Decode([]byte("owner: ana\nzone: eu"), &struct{ owner }{}))
+}
+
+type inner struct {
+	A int `json:"a"`
+}
+
+func TestDecodeUnexportedEmbedded(t *testing.T) {
+	type tagged struct {
+		inner `json:"in"`
+		B     int `json:"b"`
+	}
+
+	var result tagged
+	require.NoError(t, New(nil).Decode([]byte("a: 1\nb: 2"), &result))
+	assert.Equal(t, tagged{inner{1}, 2}, result)
+	assert.Error(t, New(nil).Decode([]byte("in:\n  a: 1"), &tagged{}))
+}
+
+func TestDecodeTimeDurationBytes(t *testing.T) {
+	type event struct {
+		At      time.Time     `json:"at"`
+		Day     *time.Time    `toon:"day,format=Jan 2, 2006"`
+		Timeout time.Duration `json:"timeout"`
+		Retry   time.Duration `json:"retry"`
+		Payload []byte        `json:"payload"`
+		Legacy  []byte        `json:"legacy"`
+	}
+
+	input := `at: "2025-03-04T05:06:07.0000008+02:00"
+day: "Mar 4, 2025"
+timeout: 1m30s
+retry: 1000
+payload: aGVsbG8=
+legacy[2]: 104,105`
+	var result event
+	require.NoError(t, New(nil).Decode([]byte(input), &result))
+
+	assert.True(t, result.At.Equal(time.Date(2025, 3, 4, 3, 6, 7, 800, time.UTC)))
+	require.NotNil(t, result.Day)
+	assert.Equal(t, time.Date(2025, 3, 4, 0, 0, 0, 0, time.UTC), *result.Day)
+	assert.Equal(t, 90*time.Second, result.Timeout)
+	assert.Equal(t, time.Microsecond, result.Retry)
+	assert.Equal(t, []byte("hello"), result.Payload)
+	assert.Equal(t, []byte("hi"), result.Legacy)
+
+	for _, input := range []string{"at: 2025-03-04", "at: 5", "timeout: soon", "payload: \"not base64\""} {
+		assert.Error(t, New(nil).Decode([]byte(input), &event{}), input)
+	}
+}
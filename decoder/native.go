@@ -0,0 +1,111 @@
+package decoder
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/Palaciodiego008/toonify/internal/types"
+	"github.com/Palaciodiego008/toonify/internal/utils"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// assignNative decodes the standard library types that TOON writes as
+// strings: RFC 3339 times, Go duration strings and base64 byte slices. It
+// reports false if dst is none of them, or if a byte slice is given as a
+// list of numbers, which is decoded item by item.
+func (d *Decoder) assignNative(src, dst reflect.Value) (bool, error) {
+	switch dstType := dst.Type(); {
+	case dstType == timeType:
+		if src.Kind() != reflect.String {
+			return true, types.NewToonError(fmt.Sprintf("cannot convert %v to time", src.Type()), 0, 0)
+		}
+		return true, d.assignTime(src.String(), time.RFC3339Nano, dst)
+	case dstType == durationType:
+		return true, d.assignDuration(src, dst)
+	case dstType.Kind() == reflect.Slice && dstType.Elem().Kind() == reflect.Uint8 && src.Kind() == reflect.String:
+		decoded, err := base64.StdEncoding.DecodeString(src.String())
+		if err != nil {
+			return true, types.NewToonError(fmt.Sprintf("cannot decode base64 bytes: %v", err), 0, 0)
+		}
+		if dst.CanSet() {
+			dst.Set(reflect.ValueOf(decoded).Convert(dstType))
+		}
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+func (d *Decoder) assignTime(s, layout string, dst reflect.Value) error {
+	t, err := time.Parse(layout, s)
+	if err != nil {
+		return types.NewToonError(fmt.Sprintf("cannot parse time from string: %s", s), 0, 0)
+	}
+	if dst.CanSet() {
+		dst.Set(reflect.ValueOf(t))
+	}
+	return nil
+}
+
+// assignDuration reads a duration string such as 1h30m, or an integer
+// number of nanoseconds
+func (d *Decoder) assignDuration(src, dst reflect.Value) error {
+	var duration time.Duration
+	switch src.Kind() {
+	case reflect.String:
+		if src.Type() == numberType {
+			n, err := numberInt(types.Number(src.String()))
+			if err != nil {
+				return err
+			}
+			if !n.IsInt64() {
+				return types.NewToonError(fmt.Sprintf("number %s overflows duration", src.String()), 0, 0)
+			}
+			duration = time.Duration(n.Int64())
+			break
+		}
+		var err error
+		duration, err = time.ParseDuration(src.String())
+		if err != nil {
+			return types.NewToonError(fmt.Sprintf("cannot parse duration from string: %s", src.String()), 0, 0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		duration = time.Duration(src.Int())
+	default:
+		return types.NewToonError(fmt.Sprintf("cannot convert %v to duration", src.Type()), 0, 0)
+	}
+
+	if dst.CanSet() {
+		dst.SetInt(int64(duration))
+	}
+	return nil
+}
+
+// assignField decodes src into a struct field, parsing a time.Time or
+// *time.Time field with the layout of its format tag option
+func (d *Decoder) assignField(src, dst reflect.Value, tag utils.FieldTag) error {
+	for src.IsValid() && src.Kind() == reflect.Interface {
+		src = src.Elem()
+	}
+	if tag.Format == "" || !src.IsValid() || src.Kind() != reflect.String {
+		return d.assignReflectValue(src, dst)
+	}
+
+	target := dst
+	if target.Kind() == reflect.Ptr && target.Type().Elem() == timeType {
+		if target.IsNil() {
+			target.Set(reflect.New(timeType))
+		}
+		target = target.Elem()
+	}
+	if target.Type() != timeType {
+		return d.assignReflectValue(src, dst)
+	}
+	return d.assignTime(src.String(), tag.Format, target)
+}
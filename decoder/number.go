@@ -0,0 +1,108 @@
+package decoder
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/Palaciodiego008/toonify/internal/types"
+	"github.com/Palaciodiego008/toonify/internal/utils"
+)
+
+var (
+	numberType     = reflect.TypeOf(types.Number(""))
+	jsonNumberType = reflect.TypeOf(json.Number(""))
+)
+
+// keepsLiteral reports whether a destination of type t receives numbers as
+// their literal: Number and json.Number, numeric kinds, which check the
+// literal fits, and types that decode themselves
+func (d *Decoder) keepsLiteral(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == numberType || t == jsonNumberType {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	ptr := reflect.PtrTo(t)
+	return ptr.Implements(unmarshalerType) || ptr.Implements(textUnmarshalerType) ||
+		(d.opts.UseJSONUnmarshaler && ptr.Implements(jsonUnmarshalerType))
+}
+
+// plainNumber returns the int64 or float64 value of n, or its literal as a
+// string if it is out of the range of a float64
+func plainNumber(n types.Number) interface{} {
+	if value, ok := utils.ParseNumber(string(n)); ok {
+		return value
+	}
+	return string(n)
+}
+
+// plainNumbers replaces the Number values inside v, a parsed value, with
+// their plain values
+func plainNumbers(v interface{}) interface{} {
+	switch val := v.(type) {
+	case types.Number:
+		return plainNumber(val)
+	case map[string]interface{}:
+		for key, value := range val {
+			val[key] = plainNumbers(value)
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = plainNumbers(item)
+		}
+	case *types.OrderedObject:
+		for key, value := range val.Values {
+			val.Values[key] = plainNumbers(value)
+		}
+	}
+	return v
+}
+
+// plainValues applies plainNumbers to src unless UseNumber is set
+func (d *Decoder) plainValues(src reflect.Value) reflect.Value {
+	if d.opts.UseNumber {
+		return src
+	}
+	switch src.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Ptr:
+		return reflect.ValueOf(plainNumbers(src.Interface()))
+	default:
+		return src
+	}
+}
+
+// numberInt reads a Number as an integer. A literal with a fraction or an
+// exponent is accepted if its value is whole, such as 1e3 or 2.0.
+func numberInt(n types.Number) (*big.Int, error) {
+	literal := string(n)
+	if i, err := strconv.ParseInt(literal, 10, 64); err == nil {
+		return big.NewInt(i), nil
+	}
+
+	// An exponent far beyond the number of digits cannot give a whole
+	// number that fits in 64 bits, and would be costly to expand
+	if i := strings.IndexAny(literal, "eE"); i >= 0 {
+		exponent, err := strconv.Atoi(literal[i+1:])
+		if limit := len(literal) + 20; err != nil || exponent > limit || exponent < -limit {
+			return nil, types.NewToonError(fmt.Sprintf("cannot parse int from number: %s", literal), 0, 0)
+		}
+	}
+
+	r, ok := new(big.Rat).SetString(literal)
+	if !ok || !r.IsInt() {
+		return nil, types.NewToonError(fmt.Sprintf("cannot parse int from number: %s", literal), 0, 0)
+	}
+	return r.Num(), nil
+}
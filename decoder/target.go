@@ -0,0 +1,71 @@
+package decoder
+
+import (
+	"reflect"
+
+	"github.com/Palaciodiego008/toonify/internal/utils"
+	"github.com/Palaciodiego008/toonify/parser"
+)
+
+// valueTarget lets the parser decode arrays straight into a destination
+// value. Struct fields are followed by name and slices receive their items
+// as they are parsed; anything else is left to assignValue.
+type valueTarget struct {
+	d        *Decoder
+	dst      reflect.Value
+	streamed bool // set once items have been decoded into dst
+}
+
+// Field returns the target for the struct field named key
+func (t *valueTarget) Field(key string) parser.Target {
+	dst := settable(t.dst)
+	if !dst.IsValid() || dst.Kind() != reflect.Struct || t.d.decodesItself(dst) {
+		return nil
+	}
+
+	field, found := utils.FindStructField(dst.Type(), key)
+	if !found {
+		return nil
+	}
+	fieldValue, ok := structField(dst, field.Index)
+	if !ok {
+		return nil
+	}
+	return &valueTarget{d: t.d, dst: fieldValue}
+}
+
+// Items decodes each item into a new element appended to a slice
+// destination
+func (t *valueTarget) Items(length int) func(item interface{}) error {
+	dst := settable(t.dst)
+	if !dst.IsValid() || dst.Kind() != reflect.Slice || !dst.CanSet() || t.d.decodesItself(dst) {
+		return nil
+	}
+
+	t.streamed = true
+	dst.Set(reflect.MakeSlice(dst.Type(), 0, utils.InitialCapacity(length)))
+	elemType := dst.Type().Elem()
+	return func(item interface{}) error {
+		elem := reflect.New(elemType).Elem()
+		if err := t.d.assignReflectValue(reflect.ValueOf(item), elem); err != nil {
+			return err
+		}
+		dst.Set(reflect.Append(dst, elem))
+		return nil
+	}
+}
+
+// settable follows pointers from v, allocating nil ones, and returns the
+// value they lead to. It returns an invalid value if v cannot be set.
+func settable(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			if !v.CanSet() {
+				return reflect.Value{}
+			}
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	return v
+}
@@ -0,0 +1,82 @@
+package decoder
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/Palaciodiego008/toonify/encoder"
+	"github.com/Palaciodiego008/toonify/internal/types"
+)
+
+var (
+	unmarshalerType     = reflect.TypeOf((*types.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// unmarshaler returns the Unmarshaler of dst, whose pointer receiver
+// methods are reachable because dst is addressable.
+func unmarshaler(dst reflect.Value) (types.Unmarshaler, bool) {
+	u, ok := implements(dst, unmarshalerType)
+	if !ok {
+		return nil, false
+	}
+	return u.(types.Unmarshaler), true
+}
+
+// implements returns the address of dst as the interface iface if it
+// implements it
+func implements(dst reflect.Value, iface reflect.Type) (interface{}, bool) {
+	if dst.Kind() == reflect.Ptr || !dst.CanAddr() || !dst.Addr().Type().Implements(iface) {
+		return nil, false
+	}
+	return dst.Addr().Interface(), true
+}
+
+// decodesItself reports whether dst decodes its own value, in which case
+// it gets the whole value at once
+func (d *Decoder) decodesItself(dst reflect.Value) bool {
+	if _, ok := implements(dst, unmarshalerType); ok {
+		return true
+	}
+	if _, ok := implements(dst, textUnmarshalerType); ok {
+		return true
+	}
+	_, ok := implements(dst, jsonUnmarshalerType)
+	return ok && d.opts.UseJSONUnmarshaler
+}
+
+// unmarshal encodes src back to TOON text and passes it to u
+func (d *Decoder) unmarshal(u types.Unmarshaler, src reflect.Value) error {
+	data, err := encoder.New(nil).Encode(src.Interface())
+	if err != nil {
+		return err
+	}
+	return u.UnmarshalTOON(data)
+}
+
+// unmarshalText passes a primitive src to u as text
+func (d *Decoder) unmarshalText(u encoding.TextUnmarshaler, src reflect.Value) error {
+	var text string
+	if err := d.assignString(src, reflect.ValueOf(&text).Elem()); err != nil {
+		return err
+	}
+	if err := u.UnmarshalText([]byte(text)); err != nil {
+		return types.NewToonError(fmt.Sprintf("error calling UnmarshalText for type %T: %v", u, err), 0, 0)
+	}
+	return nil
+}
+
+// unmarshalJSON encodes src as JSON and passes it to u
+func (d *Decoder) unmarshalJSON(u json.Unmarshaler, src reflect.Value) error {
+	data, err := json.Marshal(src.Interface())
+	if err != nil {
+		return types.NewToonError(fmt.Sprintf("cannot convert %v to JSON: %v", src.Type(), err), 0, 0)
+	}
+	if err := u.UnmarshalJSON(data); err != nil {
+		return types.NewToonError(fmt.Sprintf("error calling UnmarshalJSON for type %T: %v", u, err), 0, 0)
+	}
+	return nil
+}
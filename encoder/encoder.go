@@ -1,7 +1,13 @@
 package encoder
 
 import (
+	"bufio"
+	"bytes"
+	"encoding"
+	"encoding/json"
 	"fmt"
+	"io"
+	"math"
 	"reflect"
 	"strconv"
 	"strings"
@@ -20,22 +26,89 @@ func New(opts *types.EncodeOptions) *Encoder {
 	if opts == nil {
 		opts = types.DefaultEncodeOptions()
 	}
+	if opts.Indent <= 0 || opts.Delimiter == "" || opts.FlattenDepth <= 0 {
+		// Fill in zero values without modifying the caller's options
+		defaults := types.DefaultEncodeOptions()
+		copied := *opts
+		if copied.Indent <= 0 {
+			copied.Indent = defaults.Indent
+		}
+		if copied.Delimiter == "" {
+			copied.Delimiter = defaults.Delimiter
+		}
+		if copied.FlattenDepth <= 0 {
+			copied.FlattenDepth = defaults.FlattenDepth
+		}
+		opts = &copied
+	}
 	return &Encoder{opts: opts}
 }
 
 // Encode encodes a value to TOON format
 func (e *Encoder) Encode(v interface{}) ([]byte, error) {
-	lines, err := e.EncodeLines(v)
+	var buf bytes.Buffer
+	err := e.encode(v, func(line string) error {
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(line)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	return []byte(strings.Join(lines, "\n")), nil
+	return buf.Bytes(), nil
 }
 
 // EncodeLines encodes a value to TOON format as lines
 func (e *Encoder) EncodeLines(v interface{}) ([]string, error) {
+	var lines []string
+	err := e.encode(v, func(line string) error {
+		lines = append(lines, line)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// EncodeTo writes v to w in TOON format, each line followed by a newline.
+// Lines go through a buffer as they are produced and arrays are normalized
+// one item at a time, so memory use does not grow with the output. On error,
+// w may already have received part of the document.
+func (e *Encoder) EncodeTo(w io.Writer, v interface{}) error {
+	bw := bufio.NewWriter(w)
+	err := e.encode(v, func(line string) error {
+		if _, err := bw.WriteString(line); err != nil {
+			return err
+		}
+		return bw.WriteByte('\n')
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// encode normalizes v and passes each encoded line to emit
+func (e *Encoder) encode(v interface{}, emit func(line string) error) error {
+	if !types.ValidSpecVersion(e.opts.SpecVersion) {
+		return types.NewToonError(fmt.Sprintf("unsupported spec version %q", e.opts.SpecVersion), 0, 0)
+	}
+
+	w := &lineWriter{emit: emit}
 	normalized := e.normalizeValue(v)
-	return e.encodeValue(normalized, 0)
+	if obj, ok := normalized.(*object); ok && obj.len() == 0 && e.legacy() {
+		// toonify 1.0 wrote an empty root object as {}
+		return w.line("{}")
+	}
+	return e.encodeValue(w, normalized, 0)
+}
+
+// legacy reports whether the encoder writes toonify 1.0 syntax
+func (e *Encoder) legacy() bool {
+	return e.opts.SpecVersion == types.SpecVersionLegacy
 }
 
 func (e *Encoder) normalizeValue(v interface{}) interface{} {
@@ -43,265 +116,603 @@ func (e *Encoder) normalizeValue(v interface{}) interface{} {
 		return nil
 	}
 
+	switch obj := v.(type) {
+	case *types.OrderedObject:
+		return e.normalizeOrderedObject(obj)
+	case types.OrderedObject:
+		return e.normalizeOrderedObject(&obj)
+	}
+
 	val := reflect.ValueOf(v)
+	if val.Kind() == reflect.Ptr && val.IsNil() {
+		return nil
+	}
+	if m, ok := marshaler(val); ok {
+		return e.marshalValue(m, val.Type())
+	}
+	if n, ok := numberValue(val); ok {
+		return n
+	}
+	// MarshalText comes before the built-in forms, so that a byte slice
+	// type such as net.IP is written as text rather than base64
+	if m, ok := implements(val, textMarshalerType); ok {
+		return e.textValue(m.(encoding.TextMarshaler), val.Type())
+	}
+	if native, ok := nativeValue(val); ok {
+		return native
+	}
+	if e.opts.UseJSONMarshaler {
+		if m, ok := implements(val, jsonMarshalerType); ok {
+			return e.jsonValue(m.(json.Marshaler), val.Type())
+		}
+	}
+
 	switch val.Kind() {
 	case reflect.Ptr:
-		if val.IsNil() {
-			return nil
-		}
 		return e.normalizeValue(val.Elem().Interface())
 	case reflect.Interface:
 		return e.normalizeValue(val.Elem().Interface())
 	case reflect.Struct:
-		result := make(map[string]interface{})
-		typ := val.Type()
-		for i := 0; i < val.NumField(); i++ {
-			field := typ.Field(i)
-			fieldValue := val.Field(i)
-			
-			// Skip unexported fields
-			if !fieldValue.CanInterface() {
-				continue
-			}
-			
-			// Get field name from json tag or field name
-			fieldName := field.Name
-			if jsonTag := field.Tag.Get("json"); jsonTag != "" {
-				if tagName := strings.Split(jsonTag, ",")[0]; tagName != "" && tagName != "-" {
-					fieldName = tagName
-				}
-			} else if toonTag := field.Tag.Get("toon"); toonTag != "" {
-				if tagName := strings.Split(toonTag, ",")[0]; tagName != "" && tagName != "-" {
-					fieldName = tagName
-				}
-			}
-			
-			result[fieldName] = e.normalizeValue(fieldValue.Interface())
-		}
-		return result
+		return e.normalizeStruct(val)
 	case reflect.Map:
-		result := make(map[string]interface{})
+		result := newObject(val.Len())
 		for _, key := range val.MapKeys() {
-			keyStr := fmt.Sprintf("%v", key.Interface())
-			result[keyStr] = e.normalizeValue(val.MapIndex(key).Interface())
+			keyStr, err := mapKey(key)
+			if err != nil {
+				return &marshalError{err}
+			}
+			result.set(keyStr, e.normalizeValue(val.MapIndex(key).Interface()))
 		}
+		// Go map iteration order is random, so map keys are sorted
+		e.sortKeys(result.keys)
+		result.keys = e.prioritizeKeys(result.keys)
 		return result
 	case reflect.Slice, reflect.Array:
-		result := make([]interface{}, val.Len())
-		for i := 0; i < val.Len(); i++ {
-			result[i] = e.normalizeValue(val.Index(i).Interface())
+		// Items are normalized as they are read
+		return &array{
+			length: val.Len(),
+			item: func(i int) interface{} {
+				return e.normalizeValue(val.Index(i).Interface())
+			},
 		}
-		return result
 	default:
 		return v
 	}
 }
 
-func (e *Encoder) encodeValue(v interface{}, depth int) ([]string, error) {
-	if v == nil {
-		return []string{"null"}, nil
+// normalizeStruct converts a struct to an object. Fields keep their
+// declaration order, with the fields of embedded and inline structs in
+// place of the struct that holds them.
+func (e *Encoder) normalizeStruct(val reflect.Value) *object {
+	fields := utils.StructFields(val.Type())
+	result := newObject(len(fields))
+	// all collects every key, omitted or not, once a field has been omitted
+	var all []string
+	for _, field := range fields {
+		fieldValue, ok := fieldByIndex(val, field.Index)
+		if !ok {
+			continue
+		}
+		if field.Inline {
+			n := len(result.keys)
+			e.inlineMap(result, fieldValue, fields)
+			if all != nil {
+				all = append(all, result.keys[n:]...)
+			}
+			continue
+		}
+		if field.Tag.OmitEmpty && utils.IsEmptyValue(fieldValue) {
+			if all == nil {
+				all = append(make([]string, 0, len(fields)), result.keys...)
+			}
+			all = append(all, field.Name)
+			continue
+		}
+		if all != nil {
+			all = append(all, field.Name)
+		}
+
+		value := e.normalizeValue(fieldValue.Interface())
+		if field.Tag.String {
+			value = e.stringOption(value)
+		}
+		if field.Tag.Format != "" {
+			if formatted, ok := formatTime(fieldValue, field.Tag.Format); ok {
+				value = formatted
+			}
+		}
+		result.set(field.Name, value)
+	}
+	result.keys = e.prioritizeKeys(result.keys)
+	if all != nil {
+		result.fields = e.prioritizeKeys(all)
+	}
+	return result
+}
+
+// inlineMap adds the entries of an inline map to obj in key order. Keys
+// that belong to a struct field are left to that field.
+func (e *Encoder) inlineMap(obj *object, m reflect.Value, fields []utils.StructField) {
+	taken := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		taken[field.Name] = true
+	}
+
+	keys := make([]string, 0, m.Len())
+	for _, key := range m.MapKeys() {
+		if !taken[key.String()] {
+			keys = append(keys, key.String())
+		}
+	}
+	e.sortKeys(keys)
+	for _, key := range keys {
+		obj.set(key, e.normalizeValue(m.MapIndex(reflect.ValueOf(key).Convert(m.Type().Key())).Interface()))
 	}
+}
+
+// fieldByIndex returns the field of val at index, or false if it is
+// reached through a nil embedded pointer
+func fieldByIndex(val reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && val.Kind() == reflect.Ptr {
+			if val.IsNil() {
+				return reflect.Value{}, false
+			}
+			val = val.Elem()
+		}
+		val = val.Field(x)
+	}
+	return val, true
+}
 
+// stringOption applies the ",string" tag option, writing numbers and bools
+// as strings
+func (e *Encoder) stringOption(v interface{}) interface{} {
+	switch v.(type) {
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, number:
+		str, _ := e.encodePrimitive(v)
+		return str
+	default:
+		return v
+	}
+}
+
+// normalizeOrderedObject keeps the key order of an OrderedObject as is.
+func (e *Encoder) normalizeOrderedObject(obj *types.OrderedObject) *object {
+	result := newObject(obj.Len())
+	for _, key := range obj.Keys {
+		result.set(key, e.normalizeValue(obj.Values[key]))
+	}
+	return result
+}
+
+func (e *Encoder) encodeValue(w *lineWriter, v interface{}, depth int) error {
 	switch val := v.(type) {
+	case *object:
+		return e.encodeObject(w, val, depth)
+	case *array:
+		return e.encodeArray(w, val, depth)
+	default:
+		str, err := e.encodePrimitive(v)
+		if err != nil {
+			return err
+		}
+		return w.line(e.indent(depth) + str)
+	}
+}
+
+// encodePrimitive formats a primitive value as it appears after a key, in a
+// list item or in a delimited row.
+func (e *Encoder) encodePrimitive(v interface{}) (string, error) {
+	switch val := v.(type) {
+	case nil:
+		return "null", nil
 	case bool:
-		return []string{strconv.FormatBool(val)}, nil
+		return strconv.FormatBool(val), nil
 	case int, int8, int16, int32, int64:
-		return []string{fmt.Sprintf("%d", val)}, nil
+		return fmt.Sprintf("%d", val), nil
 	case uint, uint8, uint16, uint32, uint64:
-		return []string{fmt.Sprintf("%d", val)}, nil
-	case float32, float64:
-		return []string{fmt.Sprintf("%g", val)}, nil
+		return fmt.Sprintf("%d", val), nil
+	case float32:
+		return formatFloat(float64(val), 32), nil
+	case float64:
+		return formatFloat(val, 64), nil
 	case string:
-		return []string{e.encodeString(val)}, nil
-	case map[string]interface{}:
-		return e.encodeObject(val, depth)
-	case []interface{}:
-		return e.encodeArray(val, depth)
+		if e.legacy() && strings.ContainsAny(val, "\n\r") {
+			// toonify 1.0 had no escape sequence for line breaks
+			return "", types.NewToonError(fmt.Sprintf("string %q cannot be written in legacy syntax", val), 0, 0)
+		}
+		return e.encodeString(val), nil
+	case number:
+		return string(val), nil
+	case *marshalError:
+		return "", val.err
 	default:
-		return nil, types.NewToonError(fmt.Sprintf("unsupported type: %T", v), 0, 0)
+		return "", types.NewToonError(fmt.Sprintf("unsupported type: %T", v), 0, 0)
 	}
 }
 
 func (e *Encoder) encodeString(s string) string {
-	if utils.NeedsQuoting(s) {
-		return fmt.Sprintf(`"%s"`, strings.ReplaceAll(s, `"`, `\"`))
+	if utils.NeedsQuoting(s, string(e.opts.Delimiter)) {
+		if e.legacy() {
+			return utils.QuoteLegacy(s)
+		}
+		return utils.Quote(s)
 	}
 	return s
 }
 
-func (e *Encoder) encodeObject(obj map[string]interface{}, depth int) ([]string, error) {
-	if len(obj) == 0 {
-		return []string{"{}"}, nil
+// encodeKey quotes an object key unless it is a safe unquoted identifier.
+func (e *Encoder) encodeKey(key string) string {
+	if utils.IsValidUnquotedKey(key) {
+		return key
 	}
+	return utils.Quote(key)
+}
 
-	var lines []string
-	indent := strings.Repeat(" ", depth*e.opts.Indent)
+func (e *Encoder) encodeObject(w *lineWriter, obj *object, depth int) error {
+	return e.encodeObjectFolded(w, obj, depth, e.opts.FlattenDepth)
+}
 
-	for key, value := range obj {
-		valueLines, err := e.encodeValue(value, depth+1)
-		if err != nil {
-			return nil, err
+// encodeObjectFolded encodes obj, folding single-key chains into dotted keys
+// of at most foldBudget segments when key folding is enabled. An empty
+// object has no lines; at the root it is an empty document.
+func (e *Encoder) encodeObjectFolded(w *lineWriter, obj *object, depth, foldBudget int) error {
+	indent := e.indent(depth)
+
+	for _, key := range obj.keys {
+		value := obj.values[key]
+		// The remainder of a folded chain may only use what is left of the
+		// budget, so a chain cut short by FlattenDepth is not folded again.
+		childBudget := foldBudget
+		if e.opts.KeyFolding == types.KeyFoldingSafe {
+			var segments int
+			key, value, segments = e.foldKey(key, value, obj, foldBudget)
+			if segments > 1 {
+				childBudget -= segments
+			}
 		}
 
-		if len(valueLines) == 1 && !e.isComplexValue(value) {
-			lines = append(lines, fmt.Sprintf("%s%s: %s", indent, key, valueLines[0]))
-		} else {
-			lines = append(lines, fmt.Sprintf("%s%s:", indent, key))
-			for _, line := range valueLines {
-				lines = append(lines, fmt.Sprintf("%s%s", strings.Repeat(" ", e.opts.Indent), line))
+		key = e.encodeKey(key)
+		var err error
+		switch val := value.(type) {
+		case *array:
+			err = e.encodeKeyedArray(w, key, val, depth)
+		case *object:
+			err = w.line(fmt.Sprintf("%s%s:", indent, key))
+			switch {
+			case err != nil:
+			case val.len() > 0:
+				err = e.encodeObjectFolded(w, val, depth+1, childBudget)
+			case e.legacy():
+				err = w.line(e.indent(depth+1) + "{}")
+			}
+		default:
+			var str string
+			if str, err = e.encodePrimitive(value); err == nil {
+				err = w.line(fmt.Sprintf("%s%s: %s", indent, key, str))
 			}
 		}
+		if err != nil {
+			return err
+		}
 	}
 
-	return lines, nil
+	return nil
 }
 
-func (e *Encoder) encodeArray(arr []interface{}, depth int) ([]string, error) {
-	if len(arr) == 0 {
-		return []string{"[]"}, nil
+// foldKey collapses a chain of single-key objects starting at key into a
+// dotted path such as server.http.port, using at most budget segments. It
+// returns the folded key, the value at the end of the chain and the number
+// of segments used. Chains are only folded through identifier segments, and
+// never when the folded key would collide with a sibling key.
+func (e *Encoder) foldKey(key string, value interface{}, siblings *object, budget int) (string, interface{}, int) {
+	if !utils.IsIdentifierSegment(key) {
+		return key, value, 1
+	}
+
+	segments := []string{key}
+	for len(segments) < budget {
+		obj, ok := value.(*object)
+		if !ok || obj.len() != 1 {
+			break
+		}
+
+		next := obj.keys[0]
+		if !utils.IsIdentifierSegment(next) {
+			break
+		}
+
+		segments = append(segments, next)
+		value = obj.values[next]
 	}
 
-	// Check if array is tabular (uniform objects)
-	if e.isTabularArray(arr) {
-		return e.encodeTabularArray(arr, depth)
+	if len(segments) == 1 {
+		return key, value, 1
 	}
 
-	// Encode as regular array
-	var lines []string
-	indent := strings.Repeat(" ", depth*e.opts.Indent)
+	folded := strings.Join(segments, ".")
+	if _, exists := siblings.values[folded]; exists {
+		return key, siblings.values[key], 1
+	}
+
+	return folded, value, len(segments)
+}
 
-	for _, item := range arr {
-		itemLines, err := e.encodeValue(item, depth+1)
+// encodeKeyedArray encodes an array that is the value of an object field.
+// The length and, for tabular arrays, the field list are written on the key
+// line itself (key[N]{fields}:), as the TOON spec requires.
+func (e *Encoder) encodeKeyedArray(w *lineWriter, key string, arr *array, depth int) error {
+	if e.legacy() {
+		return e.encodeLegacyKeyedArray(w, key, arr, depth)
+	}
+	if fields, ok := e.tabularFields(arr); ok {
+		return e.encodeTabularArray(w, key, fields, arr, depth)
+	}
+	if e.isPrimitiveArray(arr) {
+		line, err := e.encodeInlineArray(key, arr)
 		if err != nil {
-			return nil, err
+			return err
 		}
+		return w.line(e.indent(depth) + line)
+	}
 
-		if len(itemLines) == 1 && !e.isComplexValue(item) {
-			lines = append(lines, fmt.Sprintf("%s- %s", indent, itemLines[0]))
-		} else {
-			lines = append(lines, fmt.Sprintf("%s-", indent))
-			for _, line := range itemLines {
-				lines = append(lines, fmt.Sprintf("%s%s", strings.Repeat(" ", e.opts.Indent), line))
-			}
+	if err := w.line(fmt.Sprintf("%s%s%s:", e.indent(depth), key, e.bracket(arr.length))); err != nil {
+		return err
+	}
+	return e.encodeListItems(w, arr, depth+1)
+}
+
+// encodeLegacyKeyedArray writes an array field in toonify 1.0 syntax: a bare
+// key line with the array below it, its tabular header or list items one
+// level deeper.
+func (e *Encoder) encodeLegacyKeyedArray(w *lineWriter, key string, arr *array, depth int) error {
+	if err := w.line(fmt.Sprintf("%s%s:", e.indent(depth), key)); err != nil {
+		return err
+	}
+	return e.encodeArray(w, arr, depth+1)
+}
+
+// encodeInlineArray encodes an array of primitives on a single header line,
+// e.g. tags[3]: a,b,c. Empty arrays are written as tags[0]:.
+func (e *Encoder) encodeInlineArray(key string, arr *array) (string, error) {
+	header := fmt.Sprintf("%s%s:", key, e.bracket(arr.length))
+	if arr.length == 0 {
+		return header, nil
+	}
+
+	values := make([]string, arr.length)
+	for i := range values {
+		valueStr, err := e.encodePrimitive(arr.take(i))
+		if err != nil {
+			return "", err
 		}
+		values[i] = valueStr
 	}
 
-	return lines, nil
+	return header + " " + strings.Join(values, string(e.opts.Delimiter)), nil
 }
 
-func (e *Encoder) isTabularArray(arr []interface{}) bool {
-	if len(arr) == 0 {
-		return false
+// encodeArray encodes a root array. Its header has no key ([N]:, [N]{f}:),
+// otherwise it follows the same forms as an array field.
+func (e *Encoder) encodeArray(w *lineWriter, arr *array, depth int) error {
+	if !e.legacy() {
+		return e.encodeKeyedArray(w, "", arr, depth)
 	}
 
-	// Check if all items are objects with the same keys
-	var firstKeys []string
-	for i, item := range arr {
-		obj, ok := item.(map[string]interface{})
-		if !ok {
-			return false
+	if arr.length == 0 {
+		return w.line(e.indent(depth) + "[]")
+	}
+	if fields, ok := e.tabularFields(arr); ok {
+		return e.encodeTabularArray(w, "", fields, arr, depth)
+	}
+	return e.encodeListItems(w, arr, depth)
+}
+
+func (e *Encoder) encodeListItems(w *lineWriter, arr *array, depth int) error {
+	indent := e.indent(depth)
+
+	for i := 0; i < arr.length; i++ {
+		item := arr.take(i)
+
+		var err error
+		switch val := item.(type) {
+		case *object, *array:
+			if e.legacy() {
+				// toonify 1.0 put nested values on the lines below a bare hyphen
+				if err = w.line(indent + "-"); err == nil {
+					err = e.encodeLegacyListItem(w, item, depth+1)
+				}
+			} else if obj, ok := val.(*object); ok {
+				err = e.encodeListItemObject(w, obj, depth)
+			} else {
+				err = e.encodeListItemArray(w, val.(*array), depth)
+			}
+		default:
+			var str string
+			if str, err = e.encodePrimitive(item); err == nil {
+				err = w.line(fmt.Sprintf("%s- %s", indent, str))
+			}
+		}
+		if err != nil {
+			return err
 		}
+	}
+
+	return nil
+}
+
+// encodeLegacyListItem encodes a nested object or array of a legacy list item
+func (e *Encoder) encodeLegacyListItem(w *lineWriter, item interface{}, depth int) error {
+	if obj, ok := item.(*object); ok && obj.len() == 0 {
+		return w.line(e.indent(depth) + "{}")
+	}
+	return e.encodeValue(w, item, depth)
+}
+
+// encodeListItemObject encodes an object as a list item at depth. The first
+// field sits on the hyphen line (- id: 1) and the remaining fields are
+// indented one level below the hyphen. An empty object is a bare hyphen.
+func (e *Encoder) encodeListItemObject(w *lineWriter, obj *object, depth int) error {
+	if obj.len() == 0 {
+		return w.line(e.indent(depth) + "-")
+	}
+
+	w.hyphenate(e.indent(depth), e.indent(depth+1))
+	return e.encodeObject(w, obj, depth+1)
+}
+
+// encodeListItemArray encodes an array nested in another array as a list
+// item. The header goes on the hyphen line (- [2]: 1,2) and any rows or
+// items are indented one level below the hyphen.
+func (e *Encoder) encodeListItemArray(w *lineWriter, arr *array, depth int) error {
+	w.hyphenate(e.indent(depth), e.indent(depth))
+	return e.encodeKeyedArray(w, "", arr, depth)
+}
 
-		var keys []string
-		for k := range obj {
-			keys = append(keys, k)
+// tabularFields returns the header fields of arr if it can be written as a
+// table: every item is an object of primitives with the same columns. A
+// field that omitempty left out of every row is not a column.
+func (e *Encoder) tabularFields(arr *array) ([]string, bool) {
+	if arr.length == 0 {
+		return nil, false
+	}
+
+	var columns []string
+	columnSet := make(map[string]bool)
+	present := make(map[string]bool)
+	for i := 0; i < arr.length; i++ {
+		obj, ok := arr.peek(i).(*object)
+		if !ok {
+			return nil, false
 		}
 
+		// Check if columns match (order doesn't matter)
 		if i == 0 {
-			firstKeys = keys
-		} else {
-			// Check if keys match (order doesn't matter)
-			if len(keys) != len(firstKeys) {
-				return false
+			columns = obj.columns()
+			for _, k := range columns {
+				columnSet[k] = true
 			}
-			keyMap := make(map[string]bool)
-			for _, k := range firstKeys {
-				keyMap[k] = true
+		} else {
+			if len(obj.columns()) != len(columns) {
+				return nil, false
 			}
-			for _, k := range keys {
-				if !keyMap[k] {
-					return false
+			for _, k := range obj.columns() {
+				if !columnSet[k] {
+					return nil, false
 				}
 			}
 		}
 
 		// Check if all values are primitives
-		for _, v := range obj {
+		for k, v := range obj.values {
 			if e.isComplexValue(v) {
-				return false
+				return nil, false
 			}
+			present[k] = true
 		}
 	}
 
-	return len(firstKeys) > 0
+	fields := make([]string, 0, len(columns))
+	for _, k := range columns {
+		if present[k] {
+			fields = append(fields, k)
+		}
+	}
+	return fields, len(fields) > 0
 }
 
-func (e *Encoder) encodeTabularArray(arr []interface{}, depth int) ([]string, error) {
-	if len(arr) == 0 {
-		return []string{"[]"}, nil
+// isPrimitiveArray reports whether every item of arr is a primitive, so the
+// array can be written inline. Empty arrays count as primitive arrays.
+func (e *Encoder) isPrimitiveArray(arr *array) bool {
+	for i := 0; i < arr.length; i++ {
+		if e.isComplexValue(arr.peek(i)) {
+			return false
+		}
 	}
+	return true
+}
 
-	indent := strings.Repeat(" ", depth*e.opts.Indent)
-
-	// Get field names from first object
-	firstObj := arr[0].(map[string]interface{})
-	var fields []string
-	for k := range firstObj {
-		fields = append(fields, k)
+// encodeTabularArray writes arr as a table with the given fields, in the
+// order of the first object. A row without one of the fields writes null.
+func (e *Encoder) encodeTabularArray(w *lineWriter, key string, fields []string, arr *array, depth int) error {
+	encodedFields := make([]string, len(fields))
+	for i, field := range fields {
+		encodedFields[i] = e.encodeKey(field)
 	}
 
-	// Create header line
-	header := fmt.Sprintf("[%d]{%s}:", len(arr), strings.Join(fields, ","))
-	lines := []string{header}
+	// Create header line. toonify 1.0 always separated fields with commas.
+	delimiter := string(e.opts.Delimiter)
+	fieldDelimiter := delimiter
+	if e.legacy() {
+		fieldDelimiter = string(types.DelimiterComma)
+	}
+	header := fmt.Sprintf("%s%s%s{%s}:", e.indent(depth), key, e.bracket(arr.length), strings.Join(encodedFields, fieldDelimiter))
+	if err := w.line(header); err != nil {
+		return err
+	}
+	rowIndent := e.indent(depth + 1)
 
 	// Create data rows
-	for _, item := range arr {
-		obj := item.(map[string]interface{})
-		var values []string
-
-		for _, field := range fields {
-			value := obj[field]
-			valueStr, err := e.primitiveToString(value)
+	values := make([]string, len(fields))
+	for i := 0; i < arr.length; i++ {
+		obj := arr.take(i).(*object)
+
+		for j, field := range fields {
+			value := obj.values[field]
+			if value == nil && e.legacy() {
+				// toonify 1.0 left null cells empty
+				values[j] = ""
+				continue
+			}
+			valueStr, err := e.encodePrimitive(value)
 			if err != nil {
-				return nil, err
+				return err
 			}
-			values = append(values, valueStr)
+			values[j] = valueStr
 		}
 
-		row := fmt.Sprintf("%s%s", indent, strings.Join(values, string(e.opts.Delimiter)))
-		lines = append(lines, row)
+		if err := w.line(rowIndent + strings.Join(values, delimiter)); err != nil {
+			return err
+		}
 	}
 
-	return lines, nil
+	return nil
 }
 
-func (e *Encoder) primitiveToString(v interface{}) (string, error) {
-	switch val := v.(type) {
-	case nil:
-		return "", nil
-	case bool:
-		return strconv.FormatBool(val), nil
-	case int, int8, int16, int32, int64:
-		return fmt.Sprintf("%d", val), nil
-	case uint, uint8, uint16, uint32, uint64:
-		return fmt.Sprintf("%d", val), nil
-	case float32, float64:
-		return fmt.Sprintf("%g", val), nil
-	case string:
-		if strings.Contains(val, string(e.opts.Delimiter)) || utils.NeedsQuoting(val) {
-			return fmt.Sprintf(`"%s"`, strings.ReplaceAll(val, `"`, `\"`)), nil
-		}
-		return val, nil
-	default:
-		return "", types.NewToonError(fmt.Sprintf("non-primitive value in tabular array: %T", v), 0, 0)
+// formatFloat writes f in canonical TOON form: plain decimal notation with
+// no exponent and no trailing zeros, -0 as 0, and NaN or ±Inf as null since
+// TOON has no literal for them.
+func formatFloat(f float64, bitSize int) string {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "null"
+	}
+	if f == 0 {
+		return "0"
 	}
+	return strconv.FormatFloat(f, 'f', -1, bitSize)
 }
 
 func (e *Encoder) isComplexValue(v interface{}) bool {
 	switch v.(type) {
-	case map[string]interface{}, []interface{}:
+	case *object, *array:
 		return true
 	default:
 		return false
 	}
 }
+
+func (e *Encoder) indent(depth int) string {
+	return strings.Repeat(" ", depth*e.opts.Indent)
+}
+
+// bracket formats the [N] length segment of an array header. Non-comma
+// delimiters are declared inside the bracket ([N\t], [N|]) so that the
+// parser knows how to split the array's values.
+func (e *Encoder) bracket(length int) string {
+	// toonify 1.0 did not declare the delimiter
+	if e.opts.Delimiter == types.DelimiterComma || e.legacy() {
+		return fmt.Sprintf("[%d]", length)
+	}
+	return fmt.Sprintf("[%d%s]", length, e.opts.Delimiter)
+}
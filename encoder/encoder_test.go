@@ -1,7 +1,17 @@
 package encoder
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"net/netip"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Palaciodiego008/toonify/internal/types"
 	"github.com/stretchr/testify/assert"
@@ -45,6 +55,30 @@ func TestEncodeObject(t *testing.T) {
 	assert.NotEmpty(t, result)
 }
 
+func TestEncodeEmptyObject(t *testing.T) {
+	enc := New(types.DefaultEncodeOptions())
+
+	tests := []struct {
+		name     string
+		input    interface{}
+		expected string
+	}{
+		// An empty root object is an empty document
+		{"root_map", map[string]interface{}{}, ""},
+		{"root_struct", struct{}{}, ""},
+		{"nested", map[string]interface{}{"a": map[string]interface{}{}}, "a:"},
+		{"list_item", []interface{}{map[string]interface{}{}}, "[1]:\n  -"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := enc.Encode(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(result))
+		})
+	}
+}
+
 func TestEncodeArray(t *testing.T) {
 	enc := New(types.DefaultEncodeOptions())
 
@@ -68,3 +102,876 @@ func TestEncodeTabularArray(t *testing.T) {
 	assert.Contains(t, string(result), "Alice")
 	assert.Contains(t, string(result), "Bob")
 }
+
+func TestEncodeKeyedTabularArray(t *testing.T) {
+	enc := New(types.DefaultEncodeOptions())
+
+	input := map[string]interface{}{
+		"users": []interface{}{
+			map[string]interface{}{"id": 1},
+			map[string]interface{}{"id": 2},
+		},
+	}
+
+	result, err := enc.Encode(input)
+	require.NoError(t, err)
+	assert.Equal(t, "users[2]{id}:\n  1\n  2", string(result))
+}
+
+func TestEncodeInlineArray(t *testing.T) {
+	enc := New(types.DefaultEncodeOptions())
+
+	tests := []struct {
+		name     string
+		input    interface{}
+		expected string
+	}{
+		{"strings", []string{"a", "b", "c"}, "tags[3]: a,b,c"},
+		{"mixed", []interface{}{"x,y", 1, true, nil}, `tags[4]: "x,y",1,true,null`},
+		{"empty", []string{}, "tags[0]:"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := enc.Encode(map[string]interface{}{"tags": tt.input})
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(result))
+		})
+	}
+}
+
+func TestEncodeDelimiters(t *testing.T) {
+	input := map[string]interface{}{
+		"rows": []interface{}{
+			map[string]interface{}{"id": 1},
+			map[string]interface{}{"id": 2},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		delimiter types.Delimiter
+		expected  string
+	}{
+		{"comma", types.DelimiterComma, "rows[2]{id}:\n  1\n  2"},
+		{"tab", types.DelimiterTab, "rows[2\t]{id}:\n  1\n  2"},
+		{"pipe", types.DelimiterPipe, "rows[2|]{id}:\n  1\n  2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := types.DefaultEncodeOptions()
+			opts.Delimiter = tt.delimiter
+
+			result, err := New(opts).Encode(input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(result))
+		})
+	}
+}
+
+func TestEncodeKeyFolding(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        map[string]interface{}
+		flattenDepth int
+		expected     string
+	}{
+		{
+			name: "chain",
+			input: map[string]interface{}{
+				"server": map[string]interface{}{"http": map[string]interface{}{"port": 8080}},
+			},
+			flattenDepth: 1000,
+			expected:     "server.http.port: 8080",
+		},
+		{
+			name: "flatten_depth",
+			input: map[string]interface{}{
+				"server": map[string]interface{}{"http": map[string]interface{}{"port": 8080}},
+			},
+			flattenDepth: 2,
+			expected:     "server.http:\n  port: 8080",
+		},
+		{
+			name: "invalid_segment",
+			input: map[string]interface{}{
+				"server": map[string]interface{}{"http-1": map[string]interface{}{"port": 8080}},
+			},
+			flattenDepth: 1000,
+			expected:     "server:\n  \"http-1\":\n    port: 8080",
+		},
+		{
+			name: "array_leaf",
+			input: map[string]interface{}{
+				"data": map[string]interface{}{"tags": []string{"a", "b"}},
+			},
+			flattenDepth: 1000,
+			expected:     "data.tags[2]: a,b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := types.DefaultEncodeOptions()
+			opts.KeyFolding = types.KeyFoldingSafe
+			opts.FlattenDepth = tt.flattenDepth
+
+			result, err := New(opts).Encode(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(result))
+		})
+	}
+}
+
+func TestEncodeKeyFoldingDefaultDepth(t *testing.T) {
+	// An options literal leaves FlattenDepth at zero, which means the default
+	opts := &types.EncodeOptions{KeyFolding: types.KeyFoldingSafe}
+	input := map[string]interface{}{
+		"server": map[string]interface{}{"http": map[string]interface{}{"port": 8080}},
+	}
+
+	result, err := New(opts).Encode(input)
+	require.NoError(t, err)
+	assert.Equal(t, "server.http.port: 8080", string(result))
+	assert.Equal(t, 0, opts.FlattenDepth)
+}
+
+func TestEncodeKeyFoldingCollision(t *testing.T) {
+	opts := types.DefaultEncodeOptions()
+	opts.KeyFolding = types.KeyFoldingSafe
+
+	input := map[string]interface{}{
+		"a":   map[string]interface{}{"b": 1},
+		"a.b": 2,
+	}
+
+	result, err := New(opts).Encode(input)
+	require.NoError(t, err)
+	assert.Contains(t, string(result), "a:\n  b: 1")
+	assert.Contains(t, string(result), "a.b: 2")
+}
+
+func TestEncodeStringQuoting(t *testing.T) {
+	enc := New(types.DefaultEncodeOptions())
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"plain", "hello world", "hello world"},
+		{"empty", "", `""`},
+		{"padded", " x ", `" x "`},
+		{"keyword", "true", `"true"`},
+		{"number", "42", `"42"`},
+		{"leading_zero", "007", `"007"`},
+		{"hyphen", "-x", `"-x"`},
+		{"bracket", "[x", `"[x"`},
+		{"colon", "a:b", `"a:b"`},
+		{"delimiter", "a,b", `"a,b"`},
+		{"escapes", "a\"b\\c\nd\re\tf", `"a\"b\\c\nd\re\tf"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := enc.Encode(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(result))
+		})
+	}
+}
+
+func TestEncodeQuotingFollowsDelimiter(t *testing.T) {
+	opts := types.DefaultEncodeOptions()
+	opts.Delimiter = types.DelimiterPipe
+
+	result, err := New(opts).Encode(map[string]interface{}{"tags": []string{"a,b", "c|d"}})
+	require.NoError(t, err)
+	assert.Equal(t, `tags[2|]: a,b|"c|d"`, string(result))
+}
+
+func TestEncodeQuotedKeys(t *testing.T) {
+	enc := New(types.DefaultEncodeOptions())
+
+	tests := []struct {
+		name     string
+		input    map[string]interface{}
+		expected string
+	}{
+		{"identifier", map[string]interface{}{"user_id.v2": 1}, "user_id.v2: 1"},
+		{"colon", map[string]interface{}{"a:b": 1}, `"a:b": 1`},
+		{"space", map[string]interface{}{"my key": 1}, `"my key": 1`},
+		{"empty", map[string]interface{}{"": 1}, `"": 1`},
+		{"escape", map[string]interface{}{"a\"b": 1}, `"a\"b": 1`},
+		{"array", map[string]interface{}{"my tags": []string{"x"}}, `"my tags"[1]: x`},
+		{"fields", map[string]interface{}{"rows": []interface{}{map[string]interface{}{"cpu %": 1}}}, "rows[1]{\"cpu %\"}:\n  1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := enc.Encode(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(result))
+		})
+	}
+}
+
+func TestEncodeKeyOrder(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+		ID   int    `json:"id"`
+		Role string `json:"role"`
+	}
+
+	t.Run("sorted_map_keys", func(t *testing.T) {
+		result, err := New(nil).Encode(map[string]interface{}{"b": 1, "c": 2, "a": 3})
+		require.NoError(t, err)
+		assert.Equal(t, "a: 3\nb: 1\nc: 2", string(result))
+	})
+
+	t.Run("struct_declaration_order", func(t *testing.T) {
+		result, err := New(nil).Encode(user{Name: "Alice", ID: 1, Role: "admin"})
+		require.NoError(t, err)
+		assert.Equal(t, "name: Alice\nid: 1\nrole: admin", string(result))
+	})
+
+	t.Run("tabular_columns", func(t *testing.T) {
+		result, err := New(nil).Encode(map[string]interface{}{
+			"users": []user{{Name: "Alice", ID: 1, Role: "admin"}, {Name: "Bob", ID: 2, Role: "user"}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "users[2]{name,id,role}:\n  Alice,1,admin\n  Bob,2,user", string(result))
+	})
+
+	t.Run("priority_keys", func(t *testing.T) {
+		opts := types.DefaultEncodeOptions()
+		opts.PriorityKeys = []string{"id", "missing"}
+
+		result, err := New(opts).Encode(map[string]interface{}{
+			"users": []interface{}{user{Name: "Alice", ID: 1, Role: "admin"}},
+			"count": 1,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "count: 1\nusers[1]{id,name,role}:\n  1,Alice,admin", string(result))
+	})
+
+	t.Run("custom_order", func(t *testing.T) {
+		opts := types.DefaultEncodeOptions()
+		opts.KeyLess = func(a, b string) bool { return a > b }
+
+		result, err := New(opts).Encode(map[string]interface{}{"b": 1, "c": 2, "a": 3})
+		require.NoError(t, err)
+		assert.Equal(t, "c: 2\nb: 1\na: 3", string(result))
+	})
+}
+
+func TestEncodeCanonicalNumbers(t *testing.T) {
+	enc := New(types.DefaultEncodeOptions())
+
+	tests := []struct {
+		name     string
+		input    interface{}
+		expected string
+	}{
+		{"large_float", 1000000.0, "n: 1000000"},
+		{"small_float", 0.000001, "n: 0.000001"},
+		{"trailing_zeros", 2.50, "n: 2.5"},
+		{"negative_zero", math.Copysign(0, -1), "n: 0"},
+		{"nan", math.NaN(), "n: null"},
+		{"inf", math.Inf(1), "n: null"},
+		{"float32", float32(0.1), "n: 0.1"},
+		{"inline", []float64{1e21, math.Inf(-1)}, "n[2]: 1000000000000000000000,null"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := enc.Encode(map[string]interface{}{"n": tt.input})
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(result))
+		})
+	}
+}
+
+func TestEncodeExactNumbers(t *testing.T) {
+	huge, _ := new(big.Int).SetString("-123456789012345678901234567890", 10)
+	third := big.NewRat(1, 3)
+
+	tests := []struct {
+		name     string
+		input    interface{}
+		expected string
+	}{
+		{"number", types.Number("18446744073709551616"), "n: 18446744073709551616"},
+		{"json_number", json.Number("0.1000000000000000055511151231257827"), "n: 0.1000000000000000055511151231257827"},
+		{"exponent", types.Number("-1.250E+3"), "n: -1250"},
+		{"small_exponent", json.Number("12e-5"), "n: 0.00012"},
+		{"negative_zero", types.Number("-0.0"), "n: 0"},
+		{"big_int", huge, "n: -123456789012345678901234567890"},
+		{"big_int_value", *big.NewInt(42), "n: 42"},
+		{"big_float", new(big.Float).SetPrec(200).SetInt(huge), "n: -123456789012345678901234567890"},
+		{"big_float_fraction", big.NewFloat(0.375), "n: 0.375"},
+		{"big_float_inf", new(big.Float).SetInf(false), "n: null"},
+		{"big_rat", big.NewRat(-7, 8), "n: -0.875"},
+		{"big_rat_repeating", third, "n: 1/3"},
+		{"inline", []*big.Int{big.NewInt(1), nil}, "n[2]: 1,null"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := New(nil).Encode(map[string]interface{}{"n": tt.input})
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(result))
+		})
+	}
+
+	type row struct {
+		ID    json.Number `json:"id"`
+		Total *big.Rat    `json:"total,string"`
+	}
+	result, err := New(nil).Encode([]row{{"9007199254740993", big.NewRat(5, 2)}})
+	require.NoError(t, err)
+	assert.Equal(t, "[1]{id,total}:\n  9007199254740993,\"2.5\"", string(result))
+
+	for _, literal := range []string{"1.", "0x10", "", "1e99999"} {
+		_, err := New(nil).Encode(json.Number(literal))
+		assert.Error(t, err, literal)
+	}
+}
+
+func TestEncodeListItemObjects(t *testing.T) {
+	enc := New(types.DefaultEncodeOptions())
+
+	tests := []struct {
+		name     string
+		input    []interface{}
+		expected string
+	}{
+		{
+			"fields",
+			[]interface{}{
+				map[string]interface{}{"id": 1, "name": "Alice"},
+				map[string]interface{}{"id": 2},
+			},
+			"items[2]:\n  - id: 1\n    name: Alice\n  - id: 2",
+		},
+		{
+			"nested_object",
+			[]interface{}{
+				map[string]interface{}{"a": map[string]interface{}{"x": 1}, "b": 2},
+			},
+			"items[1]:\n  - a:\n      x: 1\n    b: 2",
+		},
+		{
+			"tabular_array",
+			[]interface{}{
+				map[string]interface{}{"a": []interface{}{
+					map[string]interface{}{"x": 1},
+					map[string]interface{}{"x": 2},
+				}, "b": 2},
+			},
+			"items[1]:\n  - a[2]{x}:\n      1\n      2\n    b: 2",
+		},
+		{
+			"inline_array",
+			[]interface{}{
+				map[string]interface{}{"a": []int{1, 2}, "b": 2},
+				"x",
+			},
+			"items[2]:\n  - a[2]: 1,2\n    b: 2\n  - x",
+		},
+		{
+			"empty",
+			[]interface{}{map[string]interface{}{}, 1},
+			"items[2]:\n  -\n  - 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := enc.Encode(map[string]interface{}{"items": tt.input})
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(result))
+		})
+	}
+}
+
+func TestEncodeRootAndNestedArrays(t *testing.T) {
+	enc := New(types.DefaultEncodeOptions())
+
+	tests := []struct {
+		name     string
+		input    interface{}
+		expected string
+	}{
+		{"root_inline", []int{1, 2, 3}, "[3]: 1,2,3"},
+		{"root_empty", []string{}, "[0]:"},
+		{"root_tabular", []map[string]int{{"a": 1}, {"a": 2}}, "[2]{a}:\n  1\n  2"},
+		{"root_list", []interface{}{1, map[string]int{"a": 1}}, "[2]:\n  - 1\n  - a: 1"},
+		{"arrays_of_arrays", [][]float64{{1, 2.5}, {}}, "[2]:\n  - [2]: 1,2.5\n  - [0]:"},
+		{
+			"nested_complex",
+			[]interface{}{[]interface{}{[]int{1}, map[string]int{"a": 1}}, []map[string]int{{"a": 1}}},
+			"[2]:\n  - [2]:\n    - [1]: 1\n    - a: 1\n  - [1]{a}:\n    1",
+		},
+		{"field", map[string]interface{}{"m": [][]int{{1, 2}}}, "m[1]:\n  - [2]: 1,2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := enc.Encode(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(result))
+		})
+	}
+}
+
+func TestEncodeLegacySpec(t *testing.T) {
+	opts := types.DefaultEncodeOptions()
+	opts.SpecVersion = types.SpecVersionLegacy
+	enc := New(opts)
+
+	tests := []struct {
+		name     string
+		input    interface{}
+		expected string
+	}{
+		{"tabular", map[string]interface{}{"rows": []map[string]interface{}{{"a": 1, "b": nil}}}, "rows:\n  [1]{a,b}:\n    1,"},
+		{"primitive_array", map[string]interface{}{"tags": []string{"a", "b"}}, "tags:\n  - a\n  - b"},
+		{"list_items", map[string]interface{}{"items": []interface{}{map[string]int{"a": 1}, []int{2}}}, "items:\n  -\n    a: 1\n  -\n    - 2"},
+		{"empty", map[string]interface{}{"e": []int{}, "o": map[string]int{}}, "e:\n  []\no:\n  {}"},
+		{"root_tabular", []map[string]int{{"a": 1}}, "[1]{a}:\n  1"},
+		{"root_list", []int{1, 2}, "- 1\n- 2"},
+		{"root_empty", map[string]int{}, "{}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := enc.Encode(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(result))
+		})
+	}
+
+	// The delimiter is used for rows but not declared
+	opts.Delimiter = types.DelimiterPipe
+	result, err := New(opts).Encode(map[string]interface{}{"rows": []map[string]int{{"a": 1, "b": 2}}})
+	require.NoError(t, err)
+	assert.Equal(t, "rows:\n  [1]{a,b}:\n    1|2", string(result))
+}
+
+func TestEncodeSpecVersions(t *testing.T) {
+	input := map[string]interface{}{"tags": []string{"a", "b"}}
+
+	for _, version := range []string{"", types.SpecVersion2, types.SpecVersion3} {
+		opts := types.DefaultEncodeOptions()
+		opts.SpecVersion = version
+		result, err := New(opts).Encode(input)
+		require.NoError(t, err)
+		assert.Equal(t, "tags[2]: a,b", string(result))
+	}
+
+	opts := types.DefaultEncodeOptions()
+	opts.SpecVersion = "1.5"
+	_, err := New(opts).Encode(input)
+	assert.Error(t, err)
+}
+
+func TestEncodeTo(t *testing.T) {
+	inputs := []interface{}{
+		map[string]interface{}{"name": "Alice", "tags": []string{"a", "b"}},
+		[]map[string]int{{"id": 1}, {"id": 2}},
+		[]interface{}{map[string]interface{}{"id": 1, "meta": map[string]int{"x": 1}}, []int{1, 2}, "x"},
+		map[string]interface{}{},
+	}
+
+	for _, input := range inputs {
+		expected, err := New(nil).Encode(input)
+		require.NoError(t, err)
+		if len(expected) > 0 {
+			expected = append(expected, '\n')
+		}
+
+		var buf bytes.Buffer
+		require.NoError(t, New(nil).EncodeTo(&buf, input))
+		assert.Equal(t, string(expected), buf.String())
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestEncodeToErrors(t *testing.T) {
+	rows := make([]map[string]string, 10000)
+	for i := range rows {
+		rows[i] = map[string]string{"name": strings.Repeat("x", 10)}
+	}
+
+	err := New(nil).EncodeTo(failingWriter{}, rows)
+	assert.EqualError(t, err, "disk full")
+
+	err = New(nil).EncodeTo(&bytes.Buffer{}, map[string]interface{}{"ch": make(chan int)})
+	assert.Error(t, err)
+}
+
+type money struct {
+	cents    int64
+	currency string
+}
+
+func (m money) MarshalTOON() ([]byte, error) {
+	return []byte(fmt.Sprintf("%d.%02d %s", m.cents/100, m.cents%100, m.currency)), nil
+}
+
+type accountID int
+
+func (id *accountID) MarshalTOON() ([]byte, error) {
+	return []byte(fmt.Sprintf(`"acct-%d"`, int(*id))), nil
+}
+
+type point struct{ x, y int }
+
+func (p point) MarshalTOON() ([]byte, error) {
+	return []byte(fmt.Sprintf("y: %d\nx: %d", p.y, p.x)), nil
+}
+
+type failing struct{}
+
+func (failing) MarshalTOON() ([]byte, error) {
+	return nil, errors.New("boom")
+}
+
+func TestEncodeMarshaler(t *testing.T) {
+	type row struct {
+		ID    accountID `json:"id"`
+		Total money     `json:"total"`
+	}
+
+	tests := []struct {
+		name     string
+		input    interface{}
+		expected string
+	}{
+		{"primitive", money{1250, "USD"}, "12.50 USD"},
+		{"pointer receiver", map[string]interface{}{"id": accountID(7)}, "id: acct-7"},
+		{"pointer to value", map[string]*money{"a": {5, "EUR"}, "b": nil}, "a: 0.05 EUR\nb: null"},
+		{"object", map[string]point{"p": {1, 2}}, "p:\n  y: 2\n  x: 1"},
+		{"inline array", []money{{100, "USD"}, {200, "USD"}}, "[2]: 1.00 USD,2.00 USD"},
+		{"tabular rows", []row{{1, money{100, "USD"}}, {2, money{250, "EUR"}}}, "[2]{id,total}:\n  acct-1,1.00 USD\n  acct-2,2.50 EUR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := New(nil).Encode(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(result))
+		})
+	}
+}
+
+// counted counts its MarshalTOON calls. Odd ids have an extra field when
+// uneven is set, so that the array is written as a list.
+type counted struct {
+	id     int
+	uneven bool
+	calls  *int
+}
+
+func (c counted) MarshalTOON() ([]byte, error) {
+	*c.calls++
+	if c.uneven && c.id%2 == 1 {
+		return []byte(fmt.Sprintf("id: %d\nodd: true", c.id)), nil
+	}
+	return []byte(fmt.Sprintf("id: %d", c.id)), nil
+}
+
+func TestEncodeMarshalerCalledOnce(t *testing.T) {
+	encode := func(n int, uneven bool) int {
+		calls := 0
+		items := make([]counted, n)
+		for i := range items {
+			items[i] = counted{id: i, uneven: uneven, calls: &calls}
+		}
+		_, err := New(nil).Encode(map[string]interface{}{"items": items})
+		require.NoError(t, err)
+		return calls
+	}
+
+	// Each item is normalized once for both the form check and the write
+	assert.Equal(t, 1000, encode(1000, false))
+	assert.Equal(t, 1000, encode(1000, true))
+
+	// Past the kept items, an item is normalized once per pass
+	assert.Equal(t, 2*10000-maxKeptItems, encode(10000, false))
+}
+
+func TestEncodeMarshalerError(t *testing.T) {
+	_, err := New(nil).Encode(map[string]interface{}{"rows": []map[string]interface{}{{"a": failing{}}}})
+	assert.ErrorContains(t, err, "boom")
+}
+
+type level int
+
+func (l level) MarshalText() ([]byte, error) {
+	if l < 0 {
+		return nil, errors.New("negative level")
+	}
+	return []byte([]string{"low", "high"}[l]), nil
+}
+
+type version struct{ major, minor int }
+
+func (v *version) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("v%d.%d", v.major, v.minor)), nil
+}
+
+// legacyJSON only knows how to marshal itself to JSON
+type legacyJSON struct{ id int }
+
+func (l legacyJSON) MarshalJSON() ([]byte, error) {
+	if l.id < 0 {
+		return []byte("{"), nil
+	}
+	return []byte(fmt.Sprintf(`{"z":%d,"a":[true,null,"x"]}`, l.id)), nil
+}
+
+func TestEncodeTextMarshaler(t *testing.T) {
+	type row struct {
+		Level   level   `json:"level"`
+		Version version `json:"version"`
+	}
+
+	tests := []struct {
+		name     string
+		input    interface{}
+		expected string
+	}{
+		{"value", map[string]interface{}{"level": level(1)}, "level: high"},
+		{"pointer receiver", version{1, 2}, "v1.2"},
+		{"standard library", map[string]netip.Addr{"host": netip.MustParseAddr("::1")}, `host: "::1"`},
+		{"byte slice", map[string]net.IP{"ip": net.IPv4(1, 2, 3, 4)}, "ip: 1.2.3.4"},
+		{"map keys", map[level]int{1: 10, 0: 5}, "high: 10\nlow: 5"},
+		{"pointer map keys", map[version]bool{{2, 0}: true}, "v2.0: true"},
+		{"tabular rows", []row{{0, version{1, 0}}, {1, version{1, 1}}}, "[2]{level,version}:\n  low,v1.0\n  high,v1.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := New(nil).Encode(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(result))
+		})
+	}
+
+	_, err := New(nil).Encode(map[string]level{"a": -1})
+	assert.ErrorContains(t, err, "negative level")
+	_, err = New(nil).Encode(map[level]int{-1: 1})
+	assert.ErrorContains(t, err, "negative level")
+}
+
+func TestEncodeJSONMarshaler(t *testing.T) {
+	value := map[string]interface{}{"legacy": legacyJSON{7}}
+
+	// Without the option the type is encoded by reflection, and has no
+	// exported fields
+	result, err := New(nil).Encode(value)
+	require.NoError(t, err)
+	assert.Equal(t, "legacy:", string(result))
+
+	opts := types.DefaultEncodeOptions()
+	opts.UseJSONMarshaler = true
+	result, err = New(opts).Encode(value)
+	require.NoError(t, err)
+	assert.Equal(t, "legacy:\n  z: 7\n  a[3]: true,null,x", string(result))
+
+	_, err = New(opts).Encode(legacyJSON{-1})
+	assert.ErrorContains(t, err, "invalid JSON from MarshalJSON")
+}
+
+func TestEncodeStructTags(t *testing.T) {
+	type tagged struct {
+		Name    string  `json:"name" toon:"title"`
+		Secret  string  `json:"-"`
+		Hidden  string  `json:"hidden" toon:"-"`
+		Note    string  `json:"note,omitempty"`
+		Tags    []int   `toon:",omitempty"`
+		Ref     *int    `json:"ref,omitempty"`
+		Count   int     `json:"count,string"`
+		Ratio   float64 `json:"ratio,string"`
+		Enabled bool    `toon:"enabled,omitempty,string"`
+		Dash    int     `json:"-,"`
+	}
+
+	result, err := New(nil).Encode(tagged{Name: "a", Secret: "s", Hidden: "h", Count: 42, Ratio: 0.5, Dash: 1})
+	require.NoError(t, err)
+	assert.Equal(t, "title: a\ncount: \"42\"\nratio: \"0.5\"\n\"-\": 1", string(result))
+
+	ref := 0
+	result, err = New(nil).Encode(tagged{Note: "n", Tags: []int{1}, Ref: &ref, Enabled: true})
+	require.NoError(t, err)
+	assert.Equal(t, "title: \"\"\nnote: n\nTags[1]: 1\nref: 0\ncount: \"0\"\nratio: \"0\"\nenabled: \"true\"\n\"-\": 0", string(result))
+}
+
+func TestEncodeOmitEmptyColumns(t *testing.T) {
+	type row struct {
+		ID   int    `json:"id"`
+		Note string `json:"note,omitempty"`
+	}
+
+	// A column that is empty in every row is left out of the table
+	result, err := New(nil).Encode([]row{{ID: 1}, {ID: 2}})
+	require.NoError(t, err)
+	assert.Equal(t, "[2]{id}:\n  1\n  2", string(result))
+
+	// Rows that omit the field write null in its column
+	result, err = New(nil).Encode([]row{{ID: 1, Note: "x"}, {ID: 2}})
+	require.NoError(t, err)
+	assert.Equal(t, "[2]{id,note}:\n  1,x\n  2,null", string(result))
+
+	// One row that sets the field keeps a large slice tabular
+	rows := make([]row, 1000)
+	for i := range rows {
+		rows[i].ID = i
+	}
+	rows[500].Note = "x"
+	result, err = New(nil).Encode(map[string]interface{}{"rows": rows})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(result), "rows[1000]{id,note}:\n  0,null\n"))
+	assert.Contains(t, string(result), "\n  500,x\n")
+
+	// Rows with keys outside each other's columns are still list items
+	result, err = New(nil).Encode([]interface{}{row{ID: 1}, map[string]interface{}{"id": 2, "other": "y"}})
+	require.NoError(t, err)
+	assert.Equal(t, "[2]:\n  - id: 1\n  - id: 2\n    other: y", string(result))
+}
+
+type base struct {
+	ID      int `json:"id"`
+	Version int `json:"version"`
+	Note    string
+}
+
+type Audit struct {
+	Version int    `json:"version"`
+	Comment string `toon:"Note"`
+	By      string `json:"by"`
+}
+
+type Label struct {
+	Text string `json:"text"`
+}
+
+type paging struct {
+	Page int `json:"page"`
+}
+
+type response struct {
+	base
+	*Audit
+	Label  `json:"label"`
+	Status string            `json:"status"`
+	ID     string            `json:"id"`
+	Paging paging            `toon:",inline"`
+	Extra  map[string]string `toon:",inline"`
+	Nested base              `json:"nested"`
+}
+
+func TestEncodeEmbeddedStructs(t *testing.T) {
+	value := response{
+		base:   base{ID: 1, Version: 2, Note: "base"},
+		Audit:  &Audit{Version: 3, Comment: "audit", By: "ops"},
+		Label:  Label{Text: "x"},
+		Status: "ok",
+		ID:     "r1",
+		Paging: paging{Page: 4},
+		Extra:  map[string]string{"zone": "eu", "status": "shadowed", "by": "shadowed"},
+		Nested: base{ID: 5},
+	}
+
+	// The outer id wins over base's, the tagged Note over base's, the two
+	// version fields cancel out and the tagged Label is not promoted
+	result, err := New(nil).Encode(value)
+	require.NoError(t, err)
+	assert.Equal(t, `Note: audit
+by: ops
+label:
+  text: x
+status: ok
+id: r1
+page: 4
+zone: eu
+nested:
+  id: 5
+  version: 0
+  Note: ""`, string(result))
+
+	// Without Audit, base's fields are no longer shadowed
+	value.Audit = nil
+	value.Extra = nil
+	result, err = New(nil).Encode(value)
+	require.NoError(t, err)
+	assert.Equal(t, `label:
+  text: x
+status: ok
+id: r1
+page: 4
+nested:
+  id: 5
+  version: 0
+  Note: ""`, string(result))
+}
+
+type inner struct {
+	A      int `json:"a"`
+	hidden int
+}
+
+type hiddenName string
+
+func TestEncodeUnexportedEmbedded(t *testing.T) {
+	type tagged struct {
+		inner `json:"in"`
+		*paging
+		hiddenName
+		B int `json:"b"`
+	}
+
+	// The tag cannot nest an unexported struct, whose exported fields are
+	// promoted instead; unexported non-struct and pointer embeddings are
+	// ignored
+	result, err := New(nil).Encode(tagged{inner{1, 2}, &paging{3}, "x", 4})
+	require.NoError(t, err)
+	assert.Equal(t, "a: 1\nb: 4", string(result))
+}
+
+func TestEncodeTimeDurationBytes(t *testing.T) {
+	type event struct {
+		At      time.Time     `json:"at"`
+		Day     time.Time     `json:"day" toon:"day,format=2006-01-02"`
+		Stamp   *time.Time    `toon:"stamp,omitempty,format=Jan 2, 2006"`
+		Timeout time.Duration `json:"timeout"`
+		Payload []byte        `json:"payload"`
+		Empty   []byte        `json:"empty"`
+	}
+
+	at := time.Date(2025, 3, 4, 5, 6, 7, 800, time.UTC)
+	value := event{At: at, Day: at, Stamp: &at, Timeout: 90 * time.Second, Payload: []byte("hello")}
+	result, err := New(nil).Encode(value)
+	require.NoError(t, err)
+	assert.Equal(t, `at: "2025-03-04T05:06:07.0000008Z"
+day: 2025-03-04
+stamp: "Mar 4, 2025"
+timeout: 1m30s
+payload: aGVsbG8=
+empty: null`, string(result))
+
+	// The string forms are primitives, so rows of them stay tabular
+	result, err = New(nil).Encode([]event{{At: at}, {Timeout: time.Millisecond}})
+	require.NoError(t, err)
+	assert.Equal(t, `[2]{at,day,timeout,payload,empty}:
+  "2025-03-04T05:06:07.0000008Z",0001-01-01,0s,null,null
+  "0001-01-01T00:00:00Z",0001-01-01,1ms,null,null`, string(result))
+}
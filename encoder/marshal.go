@@ -0,0 +1,102 @@
+package encoder
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/Palaciodiego008/toonify/internal/types"
+	"github.com/Palaciodiego008/toonify/parser"
+)
+
+var (
+	marshalerType     = reflect.TypeOf((*types.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// marshalError stands in for a value whose MarshalTOON failed. It is
+// reported when the value is written.
+type marshalError struct {
+	err error
+}
+
+// marshaler returns the Marshaler implemented by val, calling a pointer
+// receiver method on a copy of val if needed.
+func marshaler(val reflect.Value) (types.Marshaler, bool) {
+	m, ok := implements(val, marshalerType)
+	if !ok {
+		return nil, false
+	}
+	return m.(types.Marshaler), true
+}
+
+// implements returns val as the interface iface if its type or, through a
+// copy, its pointer type implements it
+func implements(val reflect.Value, iface reflect.Type) (interface{}, bool) {
+	if val.Type().Implements(iface) {
+		return val.Interface(), true
+	}
+	if val.Kind() != reflect.Ptr && reflect.PtrTo(val.Type()).Implements(iface) {
+		ptr := reflect.New(val.Type())
+		ptr.Elem().Set(val)
+		return ptr.Interface(), true
+	}
+	return nil, false
+}
+
+// marshalValue parses the output of m.MarshalTOON and normalizes it in
+// place of the value of type typ
+func (e *Encoder) marshalValue(m types.Marshaler, typ reflect.Type) interface{} {
+	data, err := m.MarshalTOON()
+	if err != nil {
+		return &marshalError{types.NewToonError(fmt.Sprintf("error calling MarshalTOON for type %v: %v", typ, err), 0, 0)}
+	}
+
+	opts := types.DefaultDecodeOptions()
+	opts.PreserveOrder = true
+	opts.UseNumber = true
+	parsed, err := parser.Parse(string(data), opts)
+	if err != nil {
+		return &marshalError{types.NewToonError(fmt.Sprintf("invalid TOON from MarshalTOON for type %v: %v", typ, err), 0, 0)}
+	}
+	return e.normalizeValue(parsed)
+}
+
+// textValue writes the output of m.MarshalText as a string
+func (e *Encoder) textValue(m encoding.TextMarshaler, typ reflect.Type) interface{} {
+	text, err := m.MarshalText()
+	if err != nil {
+		return &marshalError{types.NewToonError(fmt.Sprintf("error calling MarshalText for type %v: %v", typ, err), 0, 0)}
+	}
+	return string(text)
+}
+
+// jsonValue converts the output of m.MarshalJSON to the TOON data model,
+// keeping the key order of its objects
+func (e *Encoder) jsonValue(m json.Marshaler, typ reflect.Type) interface{} {
+	data, err := m.MarshalJSON()
+	if err != nil {
+		return &marshalError{types.NewToonError(fmt.Sprintf("error calling MarshalJSON for type %v: %v", typ, err), 0, 0)}
+	}
+
+	parsed, err := types.UnmarshalOrderedJSONNumbers(data)
+	if err != nil {
+		return &marshalError{types.NewToonError(fmt.Sprintf("invalid JSON from MarshalJSON for type %v: %v", typ, err), 0, 0)}
+	}
+	return e.normalizeValue(parsed)
+}
+
+// mapKey returns the key that a map key is written under, using its
+// MarshalText method if it has one
+func mapKey(key reflect.Value) (string, error) {
+	if m, ok := implements(key, textMarshalerType); ok {
+		text, err := m.(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return "", types.NewToonError(fmt.Sprintf("error calling MarshalText for map key of type %v: %v", key.Type(), err), 0, 0)
+		}
+		return string(text), nil
+	}
+	return fmt.Sprintf("%v", key.Interface()), nil
+}
@@ -0,0 +1,52 @@
+package encoder
+
+import (
+	"encoding/base64"
+	"reflect"
+	"time"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// nativeValue encodes the standard library types that TOON writes as
+// strings: times in RFC 3339, durations in Go duration syntax and byte
+// slices in base64. A nil byte slice is null.
+func nativeValue(val reflect.Value) (interface{}, bool) {
+	switch {
+	case val.Type() == timeType:
+		return val.Interface().(time.Time).Format(time.RFC3339Nano), true
+	case val.Type() == durationType:
+		return time.Duration(val.Int()).String(), true
+	case isByteSlice(val.Type()):
+		if val.IsNil() {
+			return nil, true
+		}
+		return base64.StdEncoding.EncodeToString(val.Bytes()), true
+	default:
+		return nil, false
+	}
+}
+
+// isByteSlice reports whether t is a slice of bytes whose items do not
+// encode themselves, which encodes as base64 like in encoding/json
+func isByteSlice(t reflect.Type) bool {
+	if t.Kind() != reflect.Slice || t.Elem().Kind() != reflect.Uint8 {
+		return false
+	}
+	return !reflect.PtrTo(t.Elem()).Implements(marshalerType)
+}
+
+// formatTime applies a field's format tag option to a time.Time or
+// *time.Time field
+func formatTime(val reflect.Value, layout string) (string, bool) {
+	if val.Kind() == reflect.Ptr && !val.IsNil() {
+		val = val.Elem()
+	}
+	if val.Type() != timeType {
+		return "", false
+	}
+	return val.Interface().(time.Time).Format(layout), true
+}
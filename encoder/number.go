@@ -0,0 +1,144 @@
+package encoder
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/Palaciodiego008/toonify/internal/types"
+	"github.com/Palaciodiego008/toonify/internal/utils"
+)
+
+// number is a number literal in canonical form, written as is
+type number string
+
+var (
+	bigIntType   = reflect.TypeOf(big.Int{})
+	bigFloatType = reflect.TypeOf(big.Float{})
+	bigRatType   = reflect.TypeOf(big.Rat{})
+)
+
+// numberValue encodes the number types that hold more digits than int64
+// and float64: Number, json.Number, big.Int, big.Float and big.Rat, or
+// pointers to them. Their digits are written exactly; a big.Rat that has
+// no finite decimal form is written as a string such as "1/3".
+func numberValue(val reflect.Value) (interface{}, bool) {
+	v := val.Interface()
+	switch val.Type() {
+	case bigIntType, bigFloatType, bigRatType:
+		// Their methods have pointer receivers
+		ptr := reflect.New(val.Type())
+		ptr.Elem().Set(val)
+		v = ptr.Interface()
+	}
+
+	switch n := v.(type) {
+	case types.Number:
+		return literalNumber(string(n))
+	case json.Number:
+		return literalNumber(string(n))
+	case *big.Int:
+		return number(n.String()), true
+	case *big.Float:
+		if n.IsInf() {
+			return nil, true
+		}
+		return canonicalNumber(n.Text('f', -1)), true
+	case *big.Rat:
+		if places, ok := decimalPlaces(n.Denom()); ok {
+			return canonicalNumber(n.FloatString(places)), true
+		}
+		return n.String(), true
+	default:
+		return nil, false
+	}
+}
+
+// maxExponent bounds the exponent of a number literal, which is written out
+// in full
+const maxExponent = 1 << 12
+
+// literalNumber checks a number literal and puts it in canonical form
+func literalNumber(literal string) (interface{}, bool) {
+	if !utils.IsNumber(literal) {
+		return &marshalError{types.NewToonError(fmt.Sprintf("invalid number literal %q", literal), 0, 0)}, true
+	}
+	if i := strings.IndexAny(literal, "eE"); i >= 0 {
+		if exponent, err := strconv.Atoi(literal[i+1:]); err != nil || exponent > maxExponent || exponent < -maxExponent {
+			return &marshalError{types.NewToonError(fmt.Sprintf("number literal %q is out of range", literal), 0, 0)}, true
+		}
+	}
+	return canonicalNumber(literal), true
+}
+
+// canonicalNumber rewrites a valid number literal in the form the TOON spec
+// requires, without changing its value: no exponent, no leading zeros, no
+// trailing fractional zeros, and 0 for negative zero.
+func canonicalNumber(literal string) number {
+	negative := strings.HasPrefix(literal, "-")
+	literal = strings.TrimPrefix(literal, "-")
+
+	mantissa, exponent := literal, 0
+	if i := strings.IndexAny(literal, "eE"); i >= 0 {
+		mantissa = literal[:i]
+		exponent, _ = strconv.Atoi(literal[i+1:])
+	}
+	whole, fraction := mantissa, ""
+	if i := strings.IndexByte(mantissa, '.'); i >= 0 {
+		whole, fraction = mantissa[:i], mantissa[i+1:]
+	}
+
+	// point is the position of the decimal point within digits
+	digits := whole + fraction
+	point := len(whole) + exponent
+	trimmed := strings.TrimLeft(digits, "0")
+	point -= len(digits) - len(trimmed)
+	digits = strings.TrimRight(trimmed, "0")
+	if digits == "" {
+		return "0"
+	}
+
+	var result string
+	switch {
+	case point <= 0:
+		result = "0." + strings.Repeat("0", -point) + digits
+	case point >= len(digits):
+		result = digits + strings.Repeat("0", point-len(digits))
+	default:
+		result = digits[:point] + "." + digits[point:]
+	}
+	if negative {
+		result = "-" + result
+	}
+	return number(result)
+}
+
+// decimalPlaces returns the number of decimal places needed to write a
+// fraction with denominator denom exactly, if it has a finite decimal form
+func decimalPlaces(denom *big.Int) (int, bool) {
+	d := new(big.Int).Set(denom)
+	twos := int(d.TrailingZeroBits())
+	d.Rsh(d, uint(twos))
+
+	fives := 0
+	five, quo, rem := big.NewInt(5), new(big.Int), new(big.Int)
+	for {
+		quo.QuoRem(d, five, rem)
+		if rem.Sign() != 0 {
+			break
+		}
+		d.Set(quo)
+		fives++
+	}
+
+	if d.Cmp(big.NewInt(1)) != 0 {
+		return 0, false
+	}
+	if fives > twos {
+		return fives, true
+	}
+	return twos, true
+}
@@ -0,0 +1,118 @@
+package encoder
+
+import "sort"
+
+// object is a normalized TOON object whose keys are written in a fixed order
+type object struct {
+	keys   []string
+	values map[string]interface{}
+	// fields lists the keys of a struct including the fields that omitempty
+	// left out. It is nil if none were left out.
+	fields []string
+}
+
+func newObject(size int) *object {
+	return &object{
+		keys:   make([]string, 0, size),
+		values: make(map[string]interface{}, size),
+	}
+}
+
+// set stores value under key, appending key to the order if it is new.
+func (o *object) set(key string, value interface{}) {
+	if _, exists := o.values[key]; !exists {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+func (o *object) len() int {
+	return len(o.keys)
+}
+
+// columns returns the keys the object has as a table row. Fields left out
+// by omitempty are columns too, so that they do not break up the table.
+func (o *object) columns() []string {
+	if o.fields != nil {
+		return o.fields
+	}
+	return o.keys
+}
+
+// maxKeptItems bounds how many normalized items an array keeps between
+// checking its form and writing it.
+const maxKeptItems = 4096
+
+// array is a normalized TOON array. Items are normalized when they are read
+// instead of being copied up front. The first maxKeptItems items read while
+// checking the array's form are kept for the write, so they are normalized
+// once; items past them are normalized again when written, which keeps the
+// memory used by a large slice bounded.
+type array struct {
+	length int
+	item   func(i int) interface{}
+	kept   []interface{}
+}
+
+// peek returns item i while checking the array's form, keeping it for the
+// write if there is room.
+func (a *array) peek(i int) interface{} {
+	if i < len(a.kept) {
+		return a.kept[i]
+	}
+	v := a.item(i)
+	if i == len(a.kept) && i < maxKeptItems {
+		a.kept = append(a.kept, v)
+	}
+	return v
+}
+
+// take returns item i for writing, releasing it if it was kept.
+func (a *array) take(i int) interface{} {
+	if i < len(a.kept) {
+		v := a.kept[i]
+		a.kept[i] = nil
+		return v
+	}
+	return a.item(i)
+}
+
+// sortKeys orders map keys with EncodeOptions.KeyLess, or lexically when no
+// custom order is set, so that the same map always encodes the same way.
+func (e *Encoder) sortKeys(keys []string) {
+	if e.opts.KeyLess != nil {
+		sort.SliceStable(keys, func(i, j int) bool {
+			return e.opts.KeyLess(keys[i], keys[j])
+		})
+		return
+	}
+	sort.Strings(keys)
+}
+
+// prioritizeKeys moves any of EncodeOptions.PriorityKeys present in keys to
+// the front, in the order they are listed, keeping the rest in place.
+func (e *Encoder) prioritizeKeys(keys []string) []string {
+	if len(e.opts.PriorityKeys) == 0 {
+		return keys
+	}
+
+	present := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		present[key] = true
+	}
+
+	ordered := make([]string, 0, len(keys))
+	promoted := make(map[string]bool, len(e.opts.PriorityKeys))
+	for _, key := range e.opts.PriorityKeys {
+		if present[key] && !promoted[key] {
+			ordered = append(ordered, key)
+			promoted[key] = true
+		}
+	}
+	for _, key := range keys {
+		if !promoted[key] {
+			ordered = append(ordered, key)
+		}
+	}
+	return ordered
+}
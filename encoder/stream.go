@@ -0,0 +1,340 @@
+package encoder
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/Palaciodiego008/toonify/internal/types"
+)
+
+// Writer writes a TOON document piece by piece, without building the Go
+// values first. Fields are written with Key followed by a value, arrays
+// are opened with BeginTable or BeginList and closed with End, and the
+// lengths they declare are checked when they end. The first error is
+// returned again by every later call.
+type Writer struct {
+	enc    *Encoder
+	out    *bufio.Writer
+	lines  lineWriter
+	frames []*writerFrame
+	key    string // key waiting for its value
+	hasKey bool
+	done   bool // the root value is complete
+	err    error
+}
+
+type writerFrameKind int
+
+const (
+	objectWriterFrame writerFrameKind = iota
+	listWriterFrame
+	tableWriterFrame
+)
+
+// writerFrame is an object or array that has been begun but not ended
+type writerFrame struct {
+	kind   writerFrameKind
+	depth  int // depth of the lines inside it
+	length int // declared length of an array
+	count  int // fields, items or rows written so far
+	fields int // number of fields of a table
+	item   bool
+}
+
+// NewWriter returns a writer that writes to w using opts, or the default
+// options if opts is nil. Output is buffered until Flush.
+func NewWriter(w io.Writer, opts *types.EncodeOptions) *Writer {
+	out := bufio.NewWriter(w)
+	writer := &Writer{enc: New(opts), out: out}
+	writer.lines.emit = func(line string) error {
+		if _, err := out.WriteString(line); err != nil {
+			return err
+		}
+		return out.WriteByte('\n')
+	}
+
+	switch version := writer.enc.opts.SpecVersion; {
+	case !types.ValidSpecVersion(version):
+		writer.err = types.NewToonError(fmt.Sprintf("unsupported spec version %q", version), 0, 0)
+	case version == types.SpecVersionLegacy:
+		writer.err = types.NewToonError("the writer does not write legacy syntax", 0, 0)
+	}
+	return writer
+}
+
+// Key starts a field of the current object. Its value is written by the
+// next call.
+func (w *Writer) Key(key string) error {
+	if w.err != nil {
+		return w.err
+	}
+	f := w.top()
+	switch {
+	case f == nil || f.kind != objectWriterFrame:
+		return w.fail("key outside an object")
+	case w.hasKey:
+		return w.fail(fmt.Sprintf("key %q has no value", w.key))
+	}
+	w.key = key
+	w.hasKey = true
+	return nil
+}
+
+// String writes a string value
+func (w *Writer) String(s string) error {
+	return w.primitive(w.enc.encodeString(s))
+}
+
+// Int writes an integer value
+func (w *Writer) Int(i int64) error {
+	return w.primitive(strconv.FormatInt(i, 10))
+}
+
+// Float writes a number in canonical decimal form; NaN and ±Inf are
+// written as null
+func (w *Writer) Float(f float64) error {
+	return w.primitive(formatFloat(f, 64))
+}
+
+// Bool writes a boolean value
+func (w *Writer) Bool(b bool) error {
+	return w.primitive(strconv.FormatBool(b))
+}
+
+// Null writes a null value
+func (w *Writer) Null() error {
+	return w.primitive("null")
+}
+
+// BeginObject starts an object as the root value, the value of the pending
+// key or an item of the current list. Its fields are written until End.
+func (w *Writer) BeginObject() error {
+	if err := w.beginValue(); err != nil {
+		return err
+	}
+
+	f := w.top()
+	switch {
+	case f == nil:
+		w.push(&writerFrame{kind: objectWriterFrame})
+	case f.kind == objectWriterFrame:
+		if err := w.line(f.depth, w.takeKey()+":"); err != nil {
+			return err
+		}
+		w.push(&writerFrame{kind: objectWriterFrame, depth: f.depth + 1})
+	default:
+		// The first field goes on the hyphen line
+		w.lines.hyphenate(w.enc.indent(f.depth), w.enc.indent(f.depth+1))
+		w.push(&writerFrame{kind: objectWriterFrame, depth: f.depth + 1, item: true})
+	}
+	return nil
+}
+
+// BeginTable starts a tabular array of length rows with the given fields.
+// In an object, key is the field it is written under, unless Key was
+// called first; at the root and in lists key must be empty. Each row is
+// written with Row until End.
+func (w *Writer) BeginTable(key string, length int, fields []string) error {
+	if w.err != nil {
+		return w.err
+	}
+	if len(fields) == 0 {
+		return w.fail("table has no fields")
+	}
+
+	encoded := make([]string, len(fields))
+	for i, field := range fields {
+		encoded[i] = w.enc.encodeKey(field)
+	}
+	header := fmt.Sprintf("%s{%s}:", w.enc.bracket(length), strings.Join(encoded, string(w.enc.opts.Delimiter)))
+	return w.beginArray(key, header, &writerFrame{kind: tableWriterFrame, length: length, fields: len(fields)})
+}
+
+// BeginList starts an array of length items, placed like BeginTable. Each
+// item is written with a value method or a Begin method until End.
+func (w *Writer) BeginList(key string, length int) error {
+	if w.err != nil {
+		return w.err
+	}
+	return w.beginArray(key, w.enc.bracket(length)+":", &writerFrame{kind: listWriterFrame, length: length})
+}
+
+// Row writes a row of the current table. Values must be primitives, one
+// per field.
+func (w *Writer) Row(values ...interface{}) error {
+	if w.err != nil {
+		return w.err
+	}
+	f := w.top()
+	switch {
+	case f == nil || f.kind != tableWriterFrame:
+		return w.fail("row outside a table")
+	case len(values) != f.fields:
+		return w.fail(fmt.Sprintf("row has %d values but the table has %d fields", len(values), f.fields))
+	case f.count == f.length:
+		return w.fail(fmt.Sprintf("table declares %d rows", f.length))
+	}
+
+	cells := make([]string, len(values))
+	for i, value := range values {
+		normalized := w.enc.normalizeValue(value)
+		if w.enc.isComplexValue(normalized) {
+			return w.fail(fmt.Sprintf("row value %d is not a primitive", i))
+		}
+		cell, err := w.enc.encodePrimitive(normalized)
+		if err != nil {
+			w.err = err
+			return err
+		}
+		cells[i] = cell
+	}
+
+	f.count++
+	return w.line(f.depth, strings.Join(cells, string(w.enc.opts.Delimiter)))
+}
+
+// End closes the innermost object or array
+func (w *Writer) End() error {
+	if w.err != nil {
+		return w.err
+	}
+	f := w.top()
+	switch {
+	case f == nil:
+		return w.fail("nothing to end")
+	case w.hasKey:
+		return w.fail(fmt.Sprintf("key %q has no value", w.key))
+	case f.kind != objectWriterFrame && f.count != f.length:
+		return w.fail(fmt.Sprintf("array declares %d entries but has %d", f.length, f.count))
+	}
+
+	w.frames = w.frames[:len(w.frames)-1]
+	if len(w.frames) == 0 {
+		w.done = true
+	}
+	if f.item && f.count == 0 {
+		// An empty object item is a bare hyphen
+		w.lines.prefix = ""
+		return w.line(f.depth-1, "-")
+	}
+	return nil
+}
+
+// Flush writes any buffered lines to the underlying writer
+func (w *Writer) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	if err := w.out.Flush(); err != nil {
+		w.err = err
+	}
+	return w.err
+}
+
+// primitive writes an encoded primitive as the root value, the value of
+// the pending key or a list item
+func (w *Writer) primitive(value string) error {
+	if err := w.beginValue(); err != nil {
+		return err
+	}
+
+	f := w.top()
+	switch {
+	case f == nil:
+		w.done = true
+		return w.line(0, value)
+	case f.kind == objectWriterFrame:
+		return w.line(f.depth, w.takeKey()+": "+value)
+	default:
+		return w.line(f.depth, "- "+value)
+	}
+}
+
+// beginArray writes an array header, with the key it belongs to or a
+// hyphen, and pushes the array's frame
+func (w *Writer) beginArray(key, header string, array *writerFrame) error {
+	f := w.top()
+	if key != "" {
+		if f == nil || f.kind != objectWriterFrame {
+			return w.fail(fmt.Sprintf("array key %q outside an object", key))
+		}
+		if err := w.Key(key); err != nil {
+			return err
+		}
+	}
+	if err := w.beginValue(); err != nil {
+		return err
+	}
+
+	switch {
+	case f == nil:
+		err := w.line(0, header)
+		array.depth = 1
+		w.push(array)
+		return err
+	case f.kind == objectWriterFrame:
+		err := w.line(f.depth, w.takeKey()+header)
+		array.depth = f.depth + 1
+		w.push(array)
+		return err
+	default:
+		err := w.line(f.depth, "- "+header)
+		array.depth = f.depth + 1
+		w.push(array)
+		return err
+	}
+}
+
+// beginValue checks that a value may be written here and counts it
+func (w *Writer) beginValue() error {
+	if w.err != nil {
+		return w.err
+	}
+	f := w.top()
+	switch {
+	case f == nil && w.done:
+		return w.fail("document is already complete")
+	case f == nil:
+		return nil
+	case f.kind == tableWriterFrame:
+		return w.fail("table values must be written with Row")
+	case f.kind == objectWriterFrame && !w.hasKey:
+		return w.fail("object value without a key")
+	case f.kind == listWriterFrame && f.count == f.length:
+		return w.fail(fmt.Sprintf("list declares %d items", f.length))
+	}
+	f.count++
+	return nil
+}
+
+// takeKey returns the encoded pending key and clears it
+func (w *Writer) takeKey() string {
+	w.hasKey = false
+	return w.enc.encodeKey(w.key)
+}
+
+func (w *Writer) line(depth int, content string) error {
+	if err := w.lines.line(w.enc.indent(depth) + content); err != nil {
+		w.err = err
+	}
+	return w.err
+}
+
+func (w *Writer) push(f *writerFrame) {
+	w.frames = append(w.frames, f)
+}
+
+func (w *Writer) top() *writerFrame {
+	if len(w.frames) == 0 {
+		return nil
+	}
+	return w.frames[len(w.frames)-1]
+}
+
+func (w *Writer) fail(message string) error {
+	w.err = types.NewToonError(message, 0, 0)
+	return w.err
+}
@@ -0,0 +1,26 @@
+package encoder
+
+// lineWriter passes encoded lines on to emit. A list item's first line is
+// written by the code that encodes the item's value, so hyphenate arranges
+// for that line's indentation to be replaced with the hyphen.
+type lineWriter struct {
+	emit   func(line string) error
+	prefix string // written in place of the next line's first strip bytes
+	strip  int
+}
+
+// line writes one encoded line
+func (w *lineWriter) line(s string) error {
+	if w.prefix != "" {
+		s = w.prefix + s[w.strip:]
+		w.prefix = ""
+	}
+	return w.emit(s)
+}
+
+// hyphenate turns the next line, which starts with valueIndent, into a list
+// item at indent: "- " followed by the rest of the line.
+func (w *lineWriter) hyphenate(indent, valueIndent string) {
+	w.prefix = indent + "- "
+	w.strip = len(valueIndent)
+}
@@ -0,0 +1,37 @@
+package types
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/Palaciodiego008/toonify/internal/utils"
+)
+
+// Number is a number literal kept as written, so that integers beyond the
+// range of int64 and decimals beyond the precision of float64 are not
+// rounded. It is produced when DecodeOptions.UseNumber is set, and the
+// encoder writes it back digit for digit.
+type Number string
+
+// String returns the literal of the number
+func (n Number) String() string {
+	return string(n)
+}
+
+// Float64 returns the number as a float64
+func (n Number) Float64() (float64, error) {
+	return strconv.ParseFloat(string(n), 64)
+}
+
+// Int64 returns the number as an int64
+func (n Number) Int64() (int64, error) {
+	return strconv.ParseInt(string(n), 10, 64)
+}
+
+// MarshalJSON writes the number as a JSON number with the same literal
+func (n Number) MarshalJSON() ([]byte, error) {
+	if !utils.IsNumber(string(n)) {
+		return nil, fmt.Errorf("invalid number literal %q", string(n))
+	}
+	return []byte(n), nil
+}
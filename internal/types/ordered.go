@@ -0,0 +1,167 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// OrderedObject is a TOON object that remembers the order of its keys.
+// Values holds the entries as an Object; Keys lists them in order.
+type OrderedObject struct {
+	Keys   []string
+	Values Object
+}
+
+// NewOrderedObject creates an empty ordered object
+func NewOrderedObject() *OrderedObject {
+	return &OrderedObject{Values: make(Object)}
+}
+
+// Set stores value under key. New keys are appended to the order; existing
+// keys keep their position.
+func (o *OrderedObject) Set(key string, value Value) {
+	if o.Values == nil {
+		o.Values = make(Object)
+	}
+	if _, exists := o.Values[key]; !exists {
+		o.Keys = append(o.Keys, key)
+	}
+	o.Values[key] = value
+}
+
+// Get returns the value stored under key
+func (o *OrderedObject) Get(key string) (Value, bool) {
+	value, ok := o.Values[key]
+	return value, ok
+}
+
+// Delete removes key from the object
+func (o *OrderedObject) Delete(key string) {
+	if _, exists := o.Values[key]; !exists {
+		return
+	}
+	delete(o.Values, key)
+	for i, k := range o.Keys {
+		if k == key {
+			o.Keys = append(o.Keys[:i], o.Keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// Len returns the number of keys in the object
+func (o *OrderedObject) Len() int {
+	return len(o.Keys)
+}
+
+// MarshalJSON writes the object with its keys in order
+func (o OrderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.Keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		keyJSON, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		valueJSON, err := json.Marshal(o.Values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(keyJSON)
+		buf.WriteByte(':')
+		buf.Write(valueJSON)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON reads a JSON object, keeping its key order. Nested objects
+// become *OrderedObject values as well.
+func (o *OrderedObject) UnmarshalJSON(data []byte) error {
+	value, err := UnmarshalOrderedJSON(data)
+	if err != nil {
+		return err
+	}
+	obj, ok := value.(*OrderedObject)
+	if !ok {
+		return fmt.Errorf("cannot unmarshal %T into OrderedObject", value)
+	}
+	*o = *obj
+	return nil
+}
+
+// UnmarshalOrderedJSON parses any JSON value into the TOON data model,
+// using *OrderedObject for objects so that key order is preserved.
+func UnmarshalOrderedJSON(data []byte) (Value, error) {
+	return unmarshalOrderedJSON(data, false)
+}
+
+// UnmarshalOrderedJSONNumbers is like UnmarshalOrderedJSON, but reads
+// numbers as Number values so that none of their digits are lost.
+func UnmarshalOrderedJSONNumbers(data []byte) (Value, error) {
+	return unmarshalOrderedJSON(data, true)
+}
+
+func unmarshalOrderedJSON(data []byte, useNumber bool) (Value, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if useNumber {
+		dec.UseNumber()
+	}
+	value, err := readJSONValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err == nil {
+		return nil, fmt.Errorf("unexpected data after JSON value")
+	}
+	return value, nil
+}
+
+func readJSONValue(dec *json.Decoder) (Value, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		obj := NewOrderedObject()
+		for dec.More() {
+			keyToken, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := readJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj.Set(keyToken.(string), value)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return obj, nil
+	case json.Delim('['):
+		arr := []interface{}{}
+		for dec.More() {
+			value, err := readJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, value)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return arr, nil
+	default:
+		if number, ok := token.(json.Number); ok {
+			return Number(number), nil
+		}
+		return token, nil
+	}
+}
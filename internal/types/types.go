@@ -20,12 +20,56 @@ const (
 	DelimiterPipe  Delimiter = "|"
 )
 
+// Key folding modes for EncodeOptions.KeyFolding
+const (
+	KeyFoldingOff  = "off"
+	KeyFoldingSafe = "safe"
+)
+
+// Path expansion modes for DecodeOptions.ExpandPaths
+const (
+	ExpandPathsOff  = "off"
+	ExpandPathsSafe = "safe"
+)
+
+// Spec versions for EncodeOptions.SpecVersion and DecodeOptions.SpecVersion.
+// SpecVersionLegacy is the syntax written by toonify 1.0: tabular headers on
+// their own line below the key, lists without lengths and undeclared
+// delimiters. Versions 2 and 3 share the same syntax for everything toonify
+// reads and writes.
+const (
+	SpecVersionLegacy = "legacy"
+	SpecVersion2      = "2"
+	SpecVersion3      = "3"
+)
+
+// ValidSpecVersion reports whether v is a supported spec version. The empty
+// string selects the default.
+func ValidSpecVersion(v string) bool {
+	switch v {
+	case "", SpecVersionLegacy, SpecVersion2, SpecVersion3:
+		return true
+	}
+	return false
+}
+
 // EncodeOptions configures TOON encoding behavior
 type EncodeOptions struct {
-	Indent        int       `json:"indent"`
-	Delimiter     Delimiter `json:"delimiter"`
-	KeyFolding    string    `json:"keyFolding"`
-	FlattenDepth  int       `json:"flattenDepth"`
+	Indent       int       `json:"indent"`
+	Delimiter    Delimiter `json:"delimiter"`
+	KeyFolding   string    `json:"keyFolding"`
+	FlattenDepth int       `json:"flattenDepth"`
+	SpecVersion  string    `json:"specVersion"`
+
+	// PriorityKeys are written first, in the listed order, in every object
+	// that contains them (e.g. "id"). Other keys keep their normal order.
+	PriorityKeys []string `json:"priorityKeys"`
+	// KeyLess orders map keys. When nil, map keys are sorted lexically.
+	// Struct fields always keep their declaration order.
+	KeyLess func(a, b string) bool `json:"-"`
+	// UseJSONMarshaler encodes types that implement json.Marshaler, but not
+	// Marshaler or encoding.TextMarshaler, by converting their JSON output.
+	UseJSONMarshaler bool `json:"useJSONMarshaler"`
 }
 
 // DecodeOptions configures TOON decoding behavior
@@ -33,6 +77,18 @@ type DecodeOptions struct {
 	Indent      int    `json:"indent"`
 	Strict      bool   `json:"strict"`
 	ExpandPaths string `json:"expandPaths"`
+	SpecVersion string `json:"specVersion"`
+
+	// PreserveOrder makes the parser produce *OrderedObject values instead
+	// of map[string]interface{}, keeping the document's key order.
+	PreserveOrder bool `json:"preserveOrder"`
+	// UseJSONUnmarshaler decodes into types that implement json.Unmarshaler,
+	// but not Unmarshaler or encoding.TextUnmarshaler, by passing them the
+	// value as JSON.
+	UseJSONUnmarshaler bool `json:"useJSONUnmarshaler"`
+	// UseNumber makes the parser produce Number values instead of int64 and
+	// float64, keeping every digit of the document's numbers.
+	UseNumber bool `json:"useNumber"`
 }
 
 // DefaultEncodeOptions returns default encoding options
@@ -40,8 +96,9 @@ func DefaultEncodeOptions() *EncodeOptions {
 	return &EncodeOptions{
 		Indent:       2,
 		Delimiter:    DelimiterComma,
-		KeyFolding:   "off",
+		KeyFolding:   KeyFoldingOff,
 		FlattenDepth: 1000, // Equivalent to Number.POSITIVE_INFINITY
+		SpecVersion:  SpecVersion3,
 	}
 }
 
@@ -50,10 +107,23 @@ func DefaultDecodeOptions() *DecodeOptions {
 	return &DecodeOptions{
 		Indent:      2,
 		Strict:      true,
-		ExpandPaths: "off",
+		ExpandPaths: ExpandPathsOff,
+		SpecVersion: SpecVersion3,
 	}
 }
 
+// Marshaler is implemented by types that encode themselves. MarshalTOON
+// returns the TOON text of the value, which is written in its place.
+type Marshaler interface {
+	MarshalTOON() ([]byte, error)
+}
+
+// Unmarshaler is implemented by types that decode themselves. UnmarshalTOON
+// receives the TOON text of the value.
+type Unmarshaler interface {
+	UnmarshalTOON(data []byte) error
+}
+
 // ToonError represents an error during TOON processing
 type ToonError struct {
 	Message string
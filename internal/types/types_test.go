@@ -47,3 +47,52 @@ func TestValueTypes(t *testing.T) {
 	
 	assert.Len(t, values, 7)
 }
+
+func TestOrderedObject(t *testing.T) {
+	obj := NewOrderedObject()
+	obj.Set("b", 1)
+	obj.Set("a", 2)
+	obj.Set("c", 3)
+	obj.Set("b", 4)
+	obj.Delete("c")
+
+	assert.Equal(t, []string{"b", "a"}, obj.Keys)
+	value, ok := obj.Get("b")
+	assert.True(t, ok)
+	assert.Equal(t, 4, value)
+	assert.Equal(t, 2, obj.Len())
+}
+
+func TestOrderedObjectJSON(t *testing.T) {
+	input := `{"z":1,"a":{"y":[{"q":true,"b":null}],"x":"s"}}`
+
+	var obj OrderedObject
+	assert.NoError(t, obj.UnmarshalJSON([]byte(input)))
+	assert.Equal(t, []string{"z", "a"}, obj.Keys)
+
+	output, err := obj.MarshalJSON()
+	assert.NoError(t, err)
+	assert.Equal(t, input, string(output))
+}
+
+func TestNumber(t *testing.T) {
+	n := Number("12.5")
+	f, err := n.Float64()
+	assert.NoError(t, err)
+	assert.Equal(t, 12.5, f)
+	_, err = n.Int64()
+	assert.Error(t, err)
+
+	_, err = Number("1_0").MarshalJSON()
+	assert.Error(t, err)
+
+	value, err := UnmarshalOrderedJSONNumbers([]byte(`{"id":18446744073709551616,"list":[1.50,-0]}`))
+	assert.NoError(t, err)
+	obj := value.(*OrderedObject)
+	assert.Equal(t, Number("18446744073709551616"), obj.Values["id"])
+	assert.Equal(t, []interface{}{Number("1.50"), Number("-0")}, obj.Values["list"])
+
+	output, err := obj.MarshalJSON()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":18446744073709551616,"list":[1.50,-0]}`, string(output))
+}
@@ -0,0 +1,249 @@
+package utils
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FieldTag holds the options of a struct field's toon or json tag
+type FieldTag struct {
+	Name      string // key of the field, the Go field name if not tagged
+	Named     bool   // the name comes from the tag
+	Skip      bool   // tagged "-"
+	OmitEmpty bool
+	String    bool   // numbers and bools are written as strings
+	Inline    bool   // the fields or entries are merged into the parent
+	Format    string // time layout of a time.Time field
+}
+
+// ParseFieldTag reads the tag of field. A toon tag takes precedence over a
+// json tag, and "-" skips the field. Since a time layout may contain commas,
+// a format option takes the rest of the tag.
+func ParseFieldTag(field reflect.StructField) FieldTag {
+	tag, ok := field.Tag.Lookup("toon")
+	if !ok {
+		tag = field.Tag.Get("json")
+	}
+	if tag == "-" {
+		return FieldTag{Name: field.Name, Skip: true}
+	}
+
+	parts := strings.Split(tag, ",")
+	result := FieldTag{Name: parts[0], Named: parts[0] != ""}
+	if !result.Named {
+		result.Name = field.Name
+	}
+	for i, option := range parts[1:] {
+		if strings.HasPrefix(option, "format=") {
+			result.Format = strings.TrimPrefix(strings.Join(parts[i+1:], ","), "format=")
+			break
+		}
+		switch option {
+		case "omitempty":
+			result.OmitEmpty = true
+		case "string":
+			result.String = true
+		case "inline":
+			result.Inline = true
+		}
+	}
+	return result
+}
+
+// StructField is a field of a struct as it is encoded. Fields of embedded
+// structs and of struct fields tagged inline are promoted into the outer
+// struct, as encoding/json does.
+type StructField struct {
+	Name   string
+	Index  []int // path from the outer struct, for reflect.Value.FieldByIndex
+	Tag    FieldTag
+	Inline bool // a map[string]T tagged inline, whose entries become fields
+}
+
+var structFieldsCache sync.Map // map[reflect.Type][]StructField
+
+// StructFields returns the fields of the struct type t in declaration order,
+// with promoted fields in place of the struct that holds them. When several
+// fields share a name, the least nested one wins, then one named by a tag;
+// if that leaves more than one, none of them is used.
+func StructFields(t reflect.Type) []StructField {
+	if cached, ok := structFieldsCache.Load(t); ok {
+		return cached.([]StructField)
+	}
+	fields := typeFields(t)
+	structFieldsCache.Store(t, fields)
+	return fields
+}
+
+func typeFields(t reflect.Type) []StructField {
+	type level struct {
+		typ   reflect.Type
+		index []int
+	}
+
+	var fields []StructField
+	visited := map[reflect.Type]bool{}
+	next := []level{{typ: t}}
+	for len(next) > 0 {
+		current := next
+		next = nil
+
+		for _, l := range current {
+			if visited[l.typ] {
+				continue
+			}
+			visited[l.typ] = true
+
+			for i := 0; i < l.typ.NumField(); i++ {
+				field := l.typ.Field(i)
+				tag := ParseFieldTag(field)
+				if tag.Skip {
+					continue
+				}
+
+				if !field.IsExported() && (!field.Anonymous || field.Type.Kind() != reflect.Struct) {
+					continue
+				}
+
+				index := make([]int, len(l.index)+1)
+				copy(index, l.index)
+				index[len(l.index)] = i
+
+				if !field.IsExported() {
+					// Only the exported fields of an unexported embedded
+					// struct are reachable, so they are promoted even if
+					// the struct is tagged with a name
+					next = append(next, level{typ: field.Type, index: index})
+					continue
+				}
+
+				fieldType := field.Type
+				if fieldType.Kind() == reflect.Ptr && fieldType.Name() == "" {
+					fieldType = fieldType.Elem()
+				}
+				promote := (field.Anonymous && !tag.Named) || tag.Inline
+				switch {
+				case promote && fieldType.Kind() == reflect.Struct:
+					next = append(next, level{typ: fieldType, index: index})
+				case tag.Inline && field.Type.Kind() == reflect.Map && field.Type.Key().Kind() == reflect.String:
+					fields = append(fields, StructField{Index: index, Tag: tag, Inline: true})
+				default:
+					fields = append(fields, StructField{Name: tag.Name, Index: index, Tag: tag})
+				}
+			}
+		}
+	}
+
+	return dominantFields(fields)
+}
+
+// dominantFields resolves fields that share a name and returns the rest in
+// declaration order. Only the least nested inline map is kept.
+func dominantFields(fields []StructField) []StructField {
+	byName := map[string][]StructField{}
+	var names []string
+	var inline *StructField
+	for i, field := range fields {
+		if field.Inline {
+			if inline == nil || len(field.Index) < len(inline.Index) {
+				inline = &fields[i]
+			}
+			continue
+		}
+		if _, seen := byName[field.Name]; !seen {
+			names = append(names, field.Name)
+		}
+		byName[field.Name] = append(byName[field.Name], field)
+	}
+
+	var result []StructField
+	for _, name := range names {
+		if field, ok := dominantField(byName[name]); ok {
+			result = append(result, field)
+		}
+	}
+	if inline != nil {
+		result = append(result, *inline)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return indexLess(result[i].Index, result[j].Index)
+	})
+	return result
+}
+
+// dominantField picks the field that a name refers to, if there is exactly
+// one candidate at the shallowest depth, or exactly one tagged there
+func dominantField(fields []StructField) (StructField, bool) {
+	depth := len(fields[0].Index)
+	for _, field := range fields[1:] {
+		if len(field.Index) < depth {
+			depth = len(field.Index)
+		}
+	}
+
+	var shallowest, tagged []StructField
+	for _, field := range fields {
+		if len(field.Index) != depth {
+			continue
+		}
+		shallowest = append(shallowest, field)
+		if field.Tag.Named {
+			tagged = append(tagged, field)
+		}
+	}
+
+	switch {
+	case len(shallowest) == 1:
+		return shallowest[0], true
+	case len(tagged) == 1:
+		return tagged[0], true
+	default:
+		return StructField{}, false
+	}
+}
+
+func indexLess(a, b []int) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return len(a) < len(b)
+}
+
+// FindStructField finds the struct field that key decodes into, matching
+// its name exactly or, failing that, ignoring case. Promoted fields are
+// found as well; fields tagged "-" and unexported fields are not.
+func FindStructField(structType reflect.Type, key string) (StructField, bool) {
+	var fold StructField
+	folded := false
+
+	for _, field := range StructFields(structType) {
+		if field.Inline {
+			continue
+		}
+		if field.Name == key {
+			return field, true
+		}
+		if !folded && strings.EqualFold(field.Name, key) {
+			fold = field
+			folded = true
+		}
+	}
+
+	return fold, folded
+}
+
+// InlineMapField returns the map[string]T field of structType tagged
+// inline, which holds the keys that match no other field
+func InlineMapField(structType reflect.Type) (StructField, bool) {
+	for _, field := range StructFields(structType) {
+		if field.Inline {
+			return field, true
+		}
+	}
+	return StructField{}, false
+}
@@ -0,0 +1,204 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// numericLikeRegex matches strings that a TOON parser would read as a
+// number, including forms with leading zeros such as 007.
+var numericLikeRegex = regexp.MustCompile(`^-?(?:\d+(?:\.\d+)?(?:[eE][+-]?\d+)?|0\d+)$`)
+
+// numberRegex matches the number literals a TOON parser accepts. Leading
+// zeros (05) are not numbers; exponents (1e6) are.
+var numberRegex = regexp.MustCompile(`^-?(?:0|[1-9]\d*)(?:\.\d+)?(?:[eE][+-]?\d+)?$`)
+
+// unquotedKeyRegex matches object keys that may be written without quotes.
+var unquotedKeyRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// NeedsQuoting determines if a string value needs to be quoted in TOON
+// format. delimiter is the delimiter active where the value is written.
+func NeedsQuoting(s string, delimiter string) bool {
+	if s == "" {
+		return true
+	}
+
+	// Leading or trailing whitespace would be trimmed by the parser
+	if strings.TrimSpace(s) != s {
+		return true
+	}
+
+	// Check for special values
+	if s == "null" || s == "true" || s == "false" {
+		return true
+	}
+
+	// Check if it looks like a number
+	if IsNumericLike(s) {
+		return true
+	}
+
+	// A leading hyphen would be read as a list item marker
+	if strings.HasPrefix(s, "-") {
+		return true
+	}
+
+	if delimiter != "" && strings.Contains(s, delimiter) {
+		return true
+	}
+
+	// Check for structural and control characters
+	return strings.ContainsAny(s, ":\"\\[]{}\n\r\t")
+}
+
+// IsValidUnquotedKey reports whether an object key can be written without
+// quotes.
+func IsValidUnquotedKey(key string) bool {
+	return unquotedKeyRegex.MatchString(key)
+}
+
+// IsNumericLike reports whether s would be read as a number when unquoted.
+func IsNumericLike(s string) bool {
+	return numericLikeRegex.MatchString(s)
+}
+
+// IsNumber reports whether s is a valid TOON number literal.
+func IsNumber(s string) bool {
+	return numberRegex.MatchString(s)
+}
+
+// ParseNumber converts a number literal to an int64, or to a float64 if it
+// has a fraction or an exponent or is too large. Negative zero becomes 0. It
+// reports false if the value is out of the range of a float64.
+func ParseNumber(literal string) (interface{}, bool) {
+	if !strings.ContainsAny(literal, ".eE") {
+		if intVal, err := strconv.ParseInt(literal, 10, 64); err == nil {
+			return intVal, true
+		}
+	}
+	floatVal, err := strconv.ParseFloat(literal, 64)
+	if err != nil {
+		return nil, false
+	}
+	if floatVal == 0 {
+		return float64(0), true // normalise -0
+	}
+	return floatVal, true
+}
+
+// Quote wraps s in double quotes, escaping it as the TOON spec requires.
+func Quote(s string) string {
+	return `"` + Escape(s) + `"`
+}
+
+// Escape escapes backslashes, double quotes, newlines, carriage returns and
+// tabs. No other escape sequences exist in TOON.
+func Escape(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, char := range s {
+		switch char {
+		case '\\':
+			b.WriteString(`\\`)
+		case '"':
+			b.WriteString(`\"`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			b.WriteRune(char)
+		}
+	}
+	return b.String()
+}
+
+// Unescape reverses Escape on the contents of a quoted string. In strict
+// mode an unknown escape sequence is an error; otherwise it is kept as is.
+// The returned int is the byte offset of the offending backslash.
+func Unescape(s string, strict bool) (string, int, error) {
+	if !strings.Contains(s, `\`) {
+		return s, 0, nil
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		if i+1 >= len(s) {
+			if strict {
+				return "", i, fmt.Errorf("unterminated escape sequence")
+			}
+			b.WriteByte('\\')
+			continue
+		}
+
+		switch s[i+1] {
+		case '\\':
+			b.WriteByte('\\')
+		case '"':
+			b.WriteByte('"')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		default:
+			if strict {
+				return "", i, fmt.Errorf("invalid escape sequence \\%c", s[i+1])
+			}
+			b.WriteByte('\\')
+			b.WriteByte(s[i+1])
+		}
+		i++
+	}
+
+	return b.String(), 0, nil
+}
+
+// FindClosingQuote returns the index of the double quote that closes the
+// quoted string opening at s[start], skipping escaped characters. It
+// returns -1 if the string is unterminated.
+func FindClosingQuote(s string, start int) int {
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// FindLegacyClosingQuote is FindClosingQuote for the syntax of toonify 1.0,
+// which escaped double quotes but wrote backslashes as they are: a quote
+// closes the string unless a backslash comes right before it.
+func FindLegacyClosingQuote(s string, start int) int {
+	for i := start + 1; i < len(s); i++ {
+		if s[i] == '"' && s[i-1] != '\\' {
+			return i
+		}
+	}
+	return -1
+}
+
+// UnescapeLegacy resolves \", the only escape sequence toonify 1.0 wrote.
+// Other backslashes are part of the string.
+func UnescapeLegacy(s string) string {
+	return strings.ReplaceAll(s, `\"`, `"`)
+}
+
+// QuoteLegacy quotes s the way toonify 1.0 did, escaping only double quotes
+func QuoteLegacy(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
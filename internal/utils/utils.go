@@ -2,8 +2,6 @@ package utils
 
 import (
 	"reflect"
-	"strings"
-	"unicode"
 )
 
 // CountIndent counts the number of leading spaces in a line
@@ -19,66 +17,25 @@ func CountIndent(line string) int {
 	return count
 }
 
-// NeedsQuoting determines if a string needs to be quoted in TOON format
-func NeedsQuoting(s string) bool {
-	if s == "" {
-		return true
-	}
-
-	// Check for special values
-	if s == "null" || s == "true" || s == "false" {
-		return true
-	}
-
-	// Check if it looks like a number
-	if isNumeric(s) {
-		return true
-	}
-
-	// Check for special characters
-	for _, char := range s {
-		if char == ':' || char == ',' || char == '"' || char == '\n' || char == '\r' || char == '\t' {
-			return true
-		}
-		if unicode.IsSpace(char) && (char == ' ' && (strings.HasPrefix(s, " ") || strings.HasSuffix(s, " "))) {
-			return true
-		}
-	}
-
-	return false
-}
-
-// isNumeric checks if a string represents a number
-func isNumeric(s string) bool {
+// IsIdentifierSegment reports whether s can be used as one segment of a
+// folded dotted key: a letter or underscore followed by letters, digits or
+// underscores.
+func IsIdentifierSegment(s string) bool {
 	if s == "" {
 		return false
 	}
 
-	// Simple check for numeric patterns
-	hasDigit := false
-	hasDot := false
-	
 	for i, char := range s {
-		if unicode.IsDigit(char) {
-			hasDigit = true
-		} else if char == '.' {
-			if hasDot {
-				return false // Multiple dots
-			}
-			hasDot = true
-		} else if char == '-' || char == '+' {
-			if i != 0 {
-				return false // Sign not at beginning
-			}
-		} else if char == 'e' || char == 'E' {
-			// Scientific notation - simplified check
+		if char == '_' || (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') {
 			continue
-		} else {
-			return false
 		}
+		if i > 0 && char >= '0' && char <= '9' {
+			continue
+		}
+		return false
 	}
 
-	return hasDigit
+	return true
 }
 
 // SliceEqual compares two string slices for equality
@@ -96,38 +53,6 @@ func SliceEqual(a, b []string) bool {
 	return true
 }
 
-// FindStructField finds a struct field by name (case-insensitive)
-func FindStructField(structType reflect.Type, fieldName string) (reflect.StructField, bool) {
-	fieldName = strings.ToLower(fieldName)
-
-	for i := 0; i < structType.NumField(); i++ {
-		field := structType.Field(i)
-		
-		// Check field name
-		if strings.ToLower(field.Name) == fieldName {
-			return field, true
-		}
-
-		// Check json tag
-		if tag := field.Tag.Get("json"); tag != "" {
-			tagName := strings.Split(tag, ",")[0]
-			if strings.ToLower(tagName) == fieldName {
-				return field, true
-			}
-		}
-
-		// Check toon tag
-		if tag := field.Tag.Get("toon"); tag != "" {
-			tagName := strings.Split(tag, ",")[0]
-			if strings.ToLower(tagName) == fieldName {
-				return field, true
-			}
-		}
-	}
-
-	return reflect.StructField{}, false
-}
-
 // IsEmptyValue checks if a value is considered empty
 func IsEmptyValue(v reflect.Value) bool {
 	switch v.Kind() {
@@ -146,3 +71,13 @@ func IsEmptyValue(v reflect.Value) bool {
 	}
 	return false
 }
+
+// maxInitialCapacity bounds the capacity reserved for an array up front.
+// The length in an array header comes from the input, so it is not trusted.
+const maxInitialCapacity = 1024
+
+// InitialCapacity returns the capacity to reserve for an array declaring
+// length entries. Longer arrays grow as their entries are read.
+func InitialCapacity(length int) int {
+	return min(max(length, 0), maxInitialCapacity)
+}
@@ -0,0 +1,179 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Palaciodiego008/toonify/internal/types"
+	"github.com/Palaciodiego008/toonify/internal/utils"
+)
+
+// keyLine is a parsed "key: value" line or array header line
+type keyLine struct {
+	key    string
+	quoted bool
+	header *arrayHeader // set for array headers such as key[N]{fields}:
+	value  string       // text after the colon
+}
+
+// arrayHeader describes a parsed array header
+type arrayHeader struct {
+	key          string
+	quoted       bool
+	length       int
+	delimiter    types.Delimiter
+	fields       []string
+	quotedFields []bool
+	inline       string
+	line         int // position of the opening bracket, for error reports
+	column       int
+}
+
+// parseKeyLine splits content, a trimmed line starting at column, into its
+// key, optional array header and value. It reports false if content is not
+// a key line, e.g. a bare primitive.
+func (p *parser) parseKeyLine(content string, column int) (*keyLine, bool, error) {
+	line := &keyLine{}
+	rest := content
+
+	if strings.HasPrefix(content, `"`) {
+		end := utils.FindClosingQuote(content, 0)
+		if end == -1 {
+			return nil, false, types.NewToonError("unterminated quoted key", p.pos+1, column)
+		}
+		rest = strings.TrimLeft(content[end+1:], " ")
+		if !strings.HasPrefix(rest, ":") && !strings.HasPrefix(rest, "[") {
+			// A quoted primitive, not a key
+			return nil, false, nil
+		}
+
+		key, offset, err := utils.Unescape(content[1:end], p.opts.Strict)
+		if err != nil {
+			return nil, false, types.NewToonError(err.Error(), p.pos+1, column+1+offset)
+		}
+		line.key = key
+		line.quoted = true
+	} else {
+		end := strings.IndexAny(content, ":[")
+		if end == -1 {
+			return nil, false, nil
+		}
+		line.key = strings.TrimSpace(content[:end])
+		rest = content[end:]
+	}
+
+	if strings.HasPrefix(rest, "[") {
+		header, remainder, err := p.parseHeader(rest, column+len(content)-len(rest))
+		if err != nil {
+			return nil, false, err
+		}
+		if header == nil {
+			// Not a header after all, e.g. a plain value such as "[x"
+			if line.quoted {
+				return nil, false, types.NewToonError("invalid array header", p.pos+1, column)
+			}
+			return p.parseUnbracketedKeyLine(content, column)
+		}
+		header.key = line.key
+		header.quoted = line.quoted
+		line.header = header
+		rest = remainder
+	}
+
+	if !strings.HasPrefix(rest, ":") {
+		if line.quoted {
+			return nil, false, types.NewToonError("missing colon after key", p.pos+1, column+len(content)-len(rest))
+		}
+		return nil, false, nil
+	}
+
+	line.value = strings.TrimSpace(rest[1:])
+	if line.header != nil {
+		line.header.inline = line.value
+	}
+	return line, true, nil
+}
+
+// parseUnbracketedKeyLine handles an unquoted key line whose first bracket
+// does not start an array header, splitting at the first colon instead.
+func (p *parser) parseUnbracketedKeyLine(content string, column int) (*keyLine, bool, error) {
+	colonIndex := strings.Index(content, ":")
+	if colonIndex == -1 {
+		return nil, false, nil
+	}
+	return &keyLine{
+		key:   strings.TrimSpace(content[:colonIndex]),
+		value: strings.TrimSpace(content[colonIndex+1:]),
+	}, true, nil
+}
+
+// parseHeader parses the [N<delimiter>]{fields} part of an array header at
+// the start of s, returning the rest of s after it. A nil header means s
+// does not start with a well-formed length bracket.
+func (p *parser) parseHeader(s string, column int) (*arrayHeader, string, error) {
+	closeIndex := strings.Index(s, "]")
+	if closeIndex == -1 {
+		return nil, s, nil
+	}
+
+	bracket := s[1:closeIndex]
+	header := &arrayHeader{delimiter: types.DelimiterComma, line: p.pos + 1, column: column}
+	if strings.HasSuffix(bracket, "\t") || strings.HasSuffix(bracket, "|") {
+		header.delimiter = types.Delimiter(bracket[len(bracket)-1:])
+		bracket = bracket[:len(bracket)-1]
+	}
+
+	length, err := strconv.Atoi(bracket)
+	if err != nil || length < 0 || strings.HasPrefix(bracket, "+") {
+		return nil, s, nil
+	}
+	header.length = length
+
+	rest := s[closeIndex+1:]
+	if !strings.HasPrefix(rest, "{") {
+		return header, rest, nil
+	}
+
+	fieldsEnd := findFieldsEnd(rest)
+	if fieldsEnd == -1 {
+		return nil, s, types.NewToonError("unterminated field list in array header", p.pos+1, column+closeIndex+1)
+	}
+
+	fieldsColumn := column + closeIndex + 2
+	for _, field := range p.parseDelimitedValues(rest[1:fieldsEnd], header.delimiter) {
+		if strings.HasPrefix(field, `"`) {
+			name, err := p.parseQuotedString(field)
+			if err != nil {
+				return nil, s, err
+			}
+			header.fields = append(header.fields, name)
+			header.quotedFields = append(header.quotedFields, true)
+			continue
+		}
+		if field == "" {
+			return nil, s, types.NewToonError("empty field name in array header", p.pos+1, fieldsColumn)
+		}
+		header.fields = append(header.fields, field)
+		header.quotedFields = append(header.quotedFields, false)
+	}
+
+	return header, rest[fieldsEnd+1:], nil
+}
+
+// findFieldsEnd returns the index of the brace closing the field list that
+// opens at s[0], ignoring braces inside quoted field names.
+func findFieldsEnd(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			end := utils.FindClosingQuote(s, i)
+			if end == -1 {
+				return -1
+			}
+			i = end
+		case '}':
+			return i
+		}
+	}
+	return -1
+}
@@ -2,319 +2,703 @@ package parser
 
 import (
 	"fmt"
-	"regexp"
-	"strconv"
+	"io"
 	"strings"
 
 	"github.com/Palaciodiego008/toonify/internal/types"
 	"github.com/Palaciodiego008/toonify/internal/utils"
 )
 
-var (
-	tabularHeaderRegex = regexp.MustCompile(`^\[(\d+)\]\{([^}]+)\}:$`)
-	arrayHeaderRegex   = regexp.MustCompile(`^(.+)\[\]:$`)
-)
-
 // Parse parses TOON format string into a Go value
 func Parse(input string, opts *types.DecodeOptions) (interface{}, error) {
+	return ParseReader(strings.NewReader(input), opts, nil)
+}
+
+// ParseReader parses a TOON document read from r, reading lines as they are
+// needed. Arrays accepted by t, which may be nil, are passed to it item by
+// item and left out of the result; if the root value itself is such an
+// array, the result is nil.
+func ParseReader(r io.Reader, opts *types.DecodeOptions, t Target) (interface{}, error) {
 	if opts == nil {
 		opts = types.DefaultDecodeOptions()
 	}
-
-	lines := strings.Split(strings.TrimSpace(input), "\n")
-	if len(lines) == 0 {
-		return map[string]interface{}{}, nil
+	if opts.Indent <= 0 {
+		copied := *opts
+		copied.Indent = types.DefaultDecodeOptions().Indent
+		opts = &copied
+	}
+	if !types.ValidSpecVersion(opts.SpecVersion) {
+		return nil, types.NewToonError(fmt.Sprintf("unsupported spec version %q", opts.SpecVersion), 0, 0)
 	}
 
 	parser := &parser{
-		lines: lines,
-		opts:  opts,
-		pos:   0,
+		src:  newSource(r, opts),
+		opts: opts,
+		pos:  0,
 	}
 
-	return parser.parseValue(0)
+	value, err := parser.parseDocument(t)
+	// An error that cut the input short explains any error it caused
+	if parser.src.err != nil {
+		return nil, parser.src.err
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := value.(streamed); ok {
+		return nil, nil
+	}
+	if opts.PreserveOrder {
+		return value, nil
+	}
+	return toMaps(value), nil
+}
+
+// parseDocument parses the root value. An empty document is an empty object.
+// Content left after a root array or primitive is an error.
+func (p *parser) parseDocument(t Target) (interface{}, error) {
+	if ok, err := p.advance(0); err != nil {
+		return nil, err
+	} else if !ok {
+		return types.NewOrderedObject(), nil
+	}
+	value, err := p.parseValue(0, t)
+	if err != nil {
+		return nil, err
+	}
+
+	// A root primitive is read without moving past its line
+	switch value.(type) {
+	case *types.OrderedObject, []interface{}, streamed:
+	default:
+		p.pos++
+	}
+	p.src.discard(p.pos)
+	next := p.nextContentLine()
+	if line, ok := p.src.line(next); ok {
+		return nil, types.NewToonError("unexpected content after root value", next+1, utils.CountIndent(line)+1)
+	}
+	return value, nil
+}
+
+// toMaps replaces the ordered objects built by the parser with plain maps.
+func toMaps(v interface{}) interface{} {
+	switch val := v.(type) {
+	case *types.OrderedObject:
+		result := make(map[string]interface{}, val.Len())
+		for key, value := range val.Values {
+			result[key] = toMaps(value)
+		}
+		return result
+	case []interface{}:
+		for i, item := range val {
+			val[i] = toMaps(item)
+		}
+		return val
+	default:
+		return v
+	}
 }
 
 type parser struct {
-	lines []string
-	opts  *types.DecodeOptions
-	pos   int
+	src        *source
+	opts       *types.DecodeOptions
+	pos        int
+	arrayDepth int // number of arrays enclosing the current line
 }
 
-func (p *parser) parseValue(indent int) (interface{}, error) {
-	if p.pos >= len(p.lines) {
-		return nil, nil
+// legacy reports whether the input is read as toonify 1.0 syntax
+func (p *parser) legacy() bool {
+	return p.opts.SpecVersion == types.SpecVersionLegacy
+}
+
+// advance moves to the next non-blank line if it is indented by at least
+// indent, and reports whether it did. Otherwise the position is left on the
+// blank lines for an enclosing level to handle. In strict mode, blank lines
+// skipped inside an array are an error.
+func (p *parser) advance(indent int) (bool, error) {
+	p.src.discard(p.pos)
+
+	next := p.nextContentLine()
+	line, ok := p.src.line(next)
+	if !ok || utils.CountIndent(line) < indent {
+		return false, nil
+	}
+	if next > p.pos && p.opts.Strict && p.arrayDepth > 0 {
+		return false, types.NewToonError("blank line inside array", p.pos+1, 1)
 	}
+	p.pos = next
+	return true, nil
+}
 
-	result := make(map[string]interface{})
-	
-	for p.pos < len(p.lines) {
-		line := p.lines[p.pos]
-		lineIndent := utils.CountIndent(line)
+// nextContentLine returns the index of the first non-blank line from the
+// current position, or the index past the end of input.
+func (p *parser) nextContentLine() int {
+	next := p.pos
+	for {
+		line, ok := p.src.line(next)
+		if !ok || strings.TrimSpace(line) != "" {
+			return next
+		}
+		next++
+	}
+}
 
-		// Skip empty lines
-		if strings.TrimSpace(line) == "" {
-			p.pos++
-			continue
+// current returns the line at the current position
+func (p *parser) current() string {
+	line, _ := p.src.line(p.pos)
+	return line
+}
+
+func (p *parser) parseValue(indent int, t Target) (interface{}, error) {
+	// Find the first line of the value
+	if ok, err := p.advance(indent); !ok || err != nil {
+		return nil, err
+	}
+
+	line := p.current()
+	lineIndent := utils.CountIndent(line)
+	if lineIndent > indent {
+		return nil, types.NewToonError(fmt.Sprintf("unexpected indentation at line %d", p.pos+1), p.pos+1, lineIndent+1)
+	}
+
+	trimmed := strings.TrimSpace(line)
+
+	// Check for array item
+	if isListItem(trimmed) {
+		items, err := p.parseArrayItems(indent, 0, t)
+		if err != nil {
+			return nil, err
 		}
+		return items.value(), nil
+	}
 
-		// If indentation is less than expected, we're done with this level
-		if lineIndent < indent {
-			break
+	kl, ok, err := p.parseKeyLine(trimmed, lineIndent+1)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		// Single primitive value
+		return p.parsePrimitive(trimmed)
+	}
+	if isArrayHeader(kl) {
+		// Root array
+		return p.parseArray(kl.header, indent, t)
+	}
+
+	result := types.NewOrderedObject()
+	if err := p.parseFields(result, indent, t); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// parseFields reads the key lines of an object at indent into obj. t is
+// the target of the object, if any.
+func (p *parser) parseFields(obj *types.OrderedObject, indent int, t Target) error {
+	for {
+		// Stop at the end of input or when indentation drops below this level
+		if ok, err := p.advance(indent); !ok || err != nil {
+			return err
 		}
 
+		line := p.current()
+		lineIndent := utils.CountIndent(line)
+
 		// If indentation is more than expected, it's an error
 		if lineIndent > indent {
-			return nil, types.NewToonError(fmt.Sprintf("unexpected indentation at line %d", p.pos+1), p.pos+1, lineIndent)
+			return types.NewToonError(fmt.Sprintf("unexpected indentation at line %d", p.pos+1), p.pos+1, lineIndent+1)
 		}
 
 		trimmed := strings.TrimSpace(line)
-
-		// Check for object key-value pair
-		if colonIndex := strings.Index(trimmed, ":"); colonIndex != -1 {
-			key := strings.TrimSpace(trimmed[:colonIndex])
-			value := strings.TrimSpace(trimmed[colonIndex+1:])
-
-			if value == "" {
-				// Multi-line value
-				p.pos++
-				parsedValue, err := p.parseMultiLineValue(key, indent)
-				if err != nil {
-					return nil, err
-				}
-				result[key] = parsedValue
-			} else {
-				// Single-line value
-				parsedValue, err := p.parsePrimitive(value)
-				if err != nil {
-					return nil, err
-				}
-				result[key] = parsedValue
-				p.pos++
-			}
-			continue
+		kl, ok, err := p.parseKeyLine(trimmed, lineIndent+1)
+		if err != nil {
+			return err
 		}
-
-		// Check for array item
-		if strings.HasPrefix(trimmed, "- ") {
-			arrayResult, err := p.parseArrayItems(indent)
-			if err != nil {
-				return nil, err
-			}
-			return arrayResult, nil
+		if !ok {
+			return types.NewToonError(fmt.Sprintf("expected key-value pair: %s", trimmed), p.pos+1, lineIndent+1)
 		}
+		if err := p.parseField(obj, kl, indent, lineIndent+1, t); err != nil {
+			return err
+		}
+	}
+}
 
-		// Single primitive value
-		return p.parsePrimitive(trimmed)
+// parseField parses the value of kl, the key line at the current position,
+// and stores it in obj. indent is the depth of the field; nested values are
+// read one level deeper. t is the target of obj, if any.
+func (p *parser) parseField(obj *types.OrderedObject, kl *keyLine, indent, column int, t Target) error {
+	lineNo := p.pos + 1
+	if t != nil {
+		t = t.Field(kl.key)
 	}
 
-	if len(result) == 0 {
-		return nil, nil
+	var value interface{}
+	var err error
+	switch {
+	case kl.header != nil:
+		// Key-prefixed array header
+		value, err = p.parseArray(kl.header, indent, t)
+	case kl.value == "":
+		// Multi-line value
+		p.pos++
+		value, err = p.parseMultiLineValue(indent, t)
+	default:
+		// Single-line value
+		value, err = p.parsePrimitive(kl.value)
+		p.pos++
+	}
+	if err != nil {
+		return err
+	}
+	if _, ok := value.(streamed); ok {
+		return nil
 	}
 
-	return result, nil
+	return p.setKey(obj, kl.key, kl.quoted, value, lineNo, column)
 }
 
-func (p *parser) parseMultiLineValue(key string, indent int) (interface{}, error) {
-	if p.pos >= len(p.lines) {
-		return nil, nil
+func (p *parser) parseMultiLineValue(indent int, t Target) (interface{}, error) {
+	if p.legacy() {
+		return p.parseLegacyValue(indent, t)
+	}
+
+	// A key with nothing indented below it holds an empty object
+	if ok, err := p.advance(indent + 1); err != nil {
+		return nil, err
+	} else if !ok {
+		return types.NewOrderedObject(), nil
 	}
 
-	nextLine := strings.TrimSpace(p.lines[p.pos])
-	
-	// Check if next line is a tabular array header
-	if matches := tabularHeaderRegex.FindStringSubmatch(nextLine); matches != nil {
-		return p.parseTabularArray(matches, indent+p.opts.Indent)
+	nextLine := strings.TrimSpace(p.current())
+	nextLineIndent := utils.CountIndent(p.current())
+
+	// Legacy form: tabular array header on its own line below the key
+	if strings.HasPrefix(nextLine, "[") {
+		kl, ok, err := p.parseKeyLine(nextLine, nextLineIndent+1)
+		if err != nil {
+			return nil, err
+		}
+		if ok && kl.key == "" && kl.header != nil && kl.header.fields != nil && kl.value == "" {
+			p.pos++
+			return p.parseTabularArray(kl.header, indent+p.opts.Indent, t)
+		}
 	}
-	
-	// Check if next line starts with "- " (regular array)
-	nextLineIndent := utils.CountIndent(p.lines[p.pos])
+
+	// Legacy form: list items directly below a bare key
 	if nextLineIndent == indent+p.opts.Indent && strings.HasPrefix(nextLine, "- ") {
-		return p.parseArrayItems(indent + p.opts.Indent)
+		items, err := p.parseArrayItems(indent+p.opts.Indent, 0, t)
+		if err != nil {
+			return nil, err
+		}
+		return items.value(), nil
 	}
-	
+
 	// Regular multi-line value (nested object)
-	return p.parseValue(indent + p.opts.Indent)
+	return p.parseValue(indent+p.opts.Indent, t)
 }
 
-func (p *parser) parseTabularArray(matches []string, indent int) (interface{}, error) {
-	count, err := strconv.Atoi(matches[1])
-	if err != nil {
-		return nil, types.NewToonError(fmt.Sprintf("invalid array count: %s", matches[1]), p.pos+1, 0)
+// parseLegacyValue parses the value below a bare key in toonify 1.0 syntax.
+// Nested values are indented by however much their first line is, and a
+// tabular header or an empty [] or {} may sit at any indentation, even level
+// with the key.
+func (p *parser) parseLegacyValue(indent int, t Target) (interface{}, error) {
+	next := p.nextContentLine()
+	if line, ok := p.src.line(next); ok {
+		trimmed := strings.TrimSpace(line)
+		if (trimmed == "[]" || trimmed == "{}") && utils.CountIndent(line) >= indent {
+			value, err := p.parsePrimitive(trimmed)
+			p.pos = next + 1
+			return value, err
+		}
+		if strings.HasPrefix(trimmed, "[") {
+			kl, ok, err := p.parseKeyLine(trimmed, utils.CountIndent(line)+1)
+			if err != nil {
+				return nil, err
+			}
+			if ok && isArrayHeader(kl) && kl.header.fields != nil && kl.value == "" {
+				p.pos = next + 1
+				return p.parseTabularArray(kl.header, utils.CountIndent(line), t)
+			}
+		}
 	}
 
-	fieldsStr := matches[2]
-	fields := strings.Split(fieldsStr, ",")
-	for i, field := range fields {
-		fields[i] = strings.TrimSpace(field)
+	if ok, err := p.advance(indent + 1); err != nil {
+		return nil, err
+	} else if !ok {
+		return types.NewOrderedObject(), nil
+	}
+	return p.parseValue(utils.CountIndent(p.current()), t)
+}
+
+// parseArray parses an array whose header line, at indent, is the current
+// line. Inline arrays are always collected; other arrays are passed to t if
+// it accepts them.
+func (p *parser) parseArray(header *arrayHeader, indent int, t Target) (interface{}, error) {
+	if header.fields == nil && (header.inline != "" || header.length == 0) {
+		items, err := p.parseInlineArray(header)
+		if err != nil {
+			return nil, err
+		}
+		p.pos++
+		return items, p.checkLength(header, len(items), "values")
 	}
 
 	p.pos++ // Move past header
+	if header.fields != nil {
+		return p.parseTabularArray(header, indent, t)
+	}
 
-	var result []interface{}
-	for i := 0; i < count && p.pos < len(p.lines); i++ {
-		if p.pos >= len(p.lines) {
-			break
+	items, err := p.parseArrayItems(indent+p.opts.Indent, header.length, t)
+	if err != nil {
+		return nil, err
+	}
+	return items.value(), p.checkLength(header, items.count, "items")
+}
+
+// checkLength reports, in strict mode, an array whose number of entries
+// differs from the length declared in its header.
+func (p *parser) checkLength(header *arrayHeader, count int, noun string) error {
+	if !p.opts.Strict || count == header.length {
+		return nil
+	}
+	return types.NewToonError(fmt.Sprintf("array declares %d %s but has %d", header.length, noun, count), header.line, header.column)
+}
+
+// parseInlineArray decodes the primitive values written after the colon of
+// an inline array header, e.g. tags[3]: a,b,c.
+func (p *parser) parseInlineArray(header *arrayHeader) ([]interface{}, error) {
+	if header.inline == "" {
+		return []interface{}{}, nil
+	}
+
+	// The slice is sized from the values found, not the declared length
+	values := p.parseDelimitedValues(header.inline, header.delimiter)
+	items := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		parsedValue, err := p.parsePrimitive(value)
+		if err != nil {
+			return nil, err
 		}
+		items = append(items, parsedValue)
+	}
 
-		line := p.lines[p.pos]
-		lineIndent := utils.CountIndent(line)
+	return items, nil
+}
+
+// parseTabularArray reads the rows of a tabular array. Rows are indented
+// one level deeper than the header at indent.
+func (p *parser) parseTabularArray(header *arrayHeader, indent int, t Target) (interface{}, error) {
+	fields := header.fields
+	rowIndent := indent + p.opts.Indent
 
-		if lineIndent != indent+p.opts.Indent {
+	p.arrayDepth++
+	defer func() { p.arrayDepth-- }()
+
+	// toonify 1.0 did not indent rows consistently, so legacy tables are
+	// read by row count instead
+	legacy := p.legacy()
+	if legacy {
+		rowIndent = 0
+	}
+
+	rows := p.newArrayItems(header.length, t)
+	delimiterKnown := false
+	for !legacy || rows.count < header.length {
+		if legacy {
+			// A row whose cells were all null was written as a blank line,
+			// so every line up to the row count is a row
+			p.src.discard(p.pos)
+			if _, ok := p.src.line(p.pos); !ok {
+				break
+			}
+		} else if ok, err := p.advance(rowIndent); err != nil {
+			return nil, err
+		} else if !ok {
 			break
 		}
 
+		line := p.current()
+		lineIndent := utils.CountIndent(line)
+		if lineIndent != rowIndent && !legacy {
+			return nil, types.NewToonError(fmt.Sprintf("unexpected indentation at line %d", p.pos+1), p.pos+1, lineIndent+1)
+		}
+
 		trimmed := strings.TrimSpace(line)
-		values := p.parseDelimitedValues(trimmed)
+		if legacy {
+			// Keep tabs that separate empty cells at either end of the row
+			trimmed = strings.Trim(line, " \r")
+		}
+		if legacy && !delimiterKnown && trimmed != "" {
+			if header.delimiter == types.DelimiterComma {
+				header.delimiter = p.legacyDelimiter(trimmed, len(fields))
+			}
+			delimiterKnown = true
+		}
+		values := p.parseDelimitedValues(trimmed, header.delimiter)
+		if legacy && trimmed == "" {
+			values = make([]string, len(fields))
+		}
 
 		if len(values) != len(fields) {
-			return nil, types.NewToonError(fmt.Sprintf("field count mismatch at line %d: expected %d, got %d", p.pos+1, len(fields), len(values)), p.pos+1, 0)
+			return nil, types.NewToonError(fmt.Sprintf("field count mismatch at line %d: expected %d, got %d", p.pos+1, len(fields), len(values)), p.pos+1, lineIndent+1)
 		}
 
-		obj := make(map[string]interface{})
+		obj := types.NewOrderedObject()
 		for j, field := range fields {
 			parsedValue, err := p.parsePrimitive(values[j])
 			if err != nil {
 				return nil, err
 			}
-			obj[field] = parsedValue
+			if legacy && values[j] == "" {
+				// toonify 1.0 left null cells empty
+				parsedValue = nil
+			}
+			if err := p.setKey(obj, field, header.quotedFields[j], parsedValue, p.pos+1, lineIndent+1); err != nil {
+				return nil, err
+			}
 		}
 
-		result = append(result, obj)
+		if err := p.addItem(rows, obj, p.pos+1); err != nil {
+			return nil, err
+		}
 		p.pos++
 	}
 
-	return result, nil
+	return rows.value(), p.checkLength(header, rows.count, "rows")
 }
 
-func (p *parser) parseRegularArray(key string, indent int) (interface{}, error) {
-	p.pos++ // Move past header
+// legacyDelimiter picks the delimiter that splits row, the first row of a
+// legacy table, into the expected number of fields. toonify 1.0 did not
+// record the delimiter in the header.
+func (p *parser) legacyDelimiter(row string, fields int) types.Delimiter {
+	for _, delimiter := range []types.Delimiter{types.DelimiterComma, types.DelimiterTab, types.DelimiterPipe} {
+		if len(p.parseDelimitedValues(row, delimiter)) == fields {
+			return delimiter
+		}
+	}
+	return types.DelimiterComma
+}
 
-	var items []interface{}
-	for p.pos < len(p.lines) {
-		line := p.lines[p.pos]
-		lineIndent := utils.CountIndent(line)
+// setKey stores value under key in obj. With ExpandPaths set to "safe",
+// unquoted dotted keys made of identifier segments are expanded into nested
+// objects and deep-merged with what is already there. Conflicting values are
+// an error in strict mode; otherwise the last value wins.
+func (p *parser) setKey(obj *types.OrderedObject, key string, quoted bool, value interface{}, line, column int) error {
+	if p.opts.ExpandPaths != types.ExpandPathsSafe {
+		if _, exists := obj.Get(key); exists && p.opts.Strict {
+			return types.NewToonError(fmt.Sprintf("duplicate key %q", key), line, column)
+		}
+		obj.Set(key, value)
+		return nil
+	}
 
-		if lineIndent < indent+p.opts.Indent {
-			break
+	path := []string{key}
+	if segments := strings.Split(key, "."); !quoted && len(segments) > 1 && allIdentifierSegments(segments) {
+		path = segments
+	}
+
+	target := obj
+	for _, segment := range path[:len(path)-1] {
+		existing, exists := target.Get(segment)
+		child, ok := existing.(*types.OrderedObject)
+		if !ok {
+			if exists && p.opts.Strict {
+				return p.pathConflict(key, line, column)
+			}
+			child = types.NewOrderedObject()
+			target.Set(segment, child)
 		}
+		target = child
+	}
 
-		if lineIndent == indent+p.opts.Indent {
-			trimmed := strings.TrimSpace(line)
-			if strings.HasPrefix(trimmed, "- ") {
-				itemValue := strings.TrimSpace(trimmed[2:])
-				if itemValue == "" {
-					// Multi-line item
-					p.pos++
-					parsedItem, err := p.parseValue(indent + p.opts.Indent*2)
-					if err != nil {
-						return nil, err
-					}
-					items = append(items, parsedItem)
-				} else {
-					// Single-line item
-					parsedItem, err := p.parsePrimitive(itemValue)
-					if err != nil {
-						return nil, err
-					}
-					items = append(items, parsedItem)
-					p.pos++
-				}
-			} else {
-				break
+	return p.mergeKey(target, path[len(path)-1], value, key, line, column)
+}
+
+// mergeKey stores value under key in target, merging objects deeply.
+func (p *parser) mergeKey(target *types.OrderedObject, key string, value interface{}, fullKey string, line, column int) error {
+	existing, exists := target.Get(key)
+	if !exists {
+		target.Set(key, value)
+		return nil
+	}
+
+	existingObj, existingIsObj := existing.(*types.OrderedObject)
+	valueObj, valueIsObj := value.(*types.OrderedObject)
+	if existingIsObj && valueIsObj {
+		for _, k := range valueObj.Keys {
+			if err := p.mergeKey(existingObj, k, valueObj.Values[k], fullKey, line, column); err != nil {
+				return err
 			}
-		} else {
-			break
 		}
+		return nil
 	}
 
-	return map[string]interface{}{key: items}, nil
+	if p.opts.Strict {
+		return p.pathConflict(fullKey, line, column)
+	}
+	target.Set(key, value)
+	return nil
 }
 
-func (p *parser) parseArrayItems(indent int) (interface{}, error) {
-	var items []interface{}
+func (p *parser) pathConflict(key string, line, column int) error {
+	return types.NewToonError(fmt.Sprintf("path expansion conflict for key %q", key), line, column)
+}
 
-	for p.pos < len(p.lines) {
-		line := p.lines[p.pos]
-		lineIndent := utils.CountIndent(line)
+func allIdentifierSegments(segments []string) bool {
+	for _, segment := range segments {
+		if !utils.IsIdentifierSegment(segment) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseArrayItems reads the list items at indent of an array declaring
+// length entries, or 0 if it has no header.
+func (p *parser) parseArrayItems(indent, length int, t Target) (*arrayItems, error) {
+	items := p.newArrayItems(length, t)
+
+	p.arrayDepth++
+	defer func() { p.arrayDepth-- }()
 
-		if lineIndent < indent {
+	for {
+		if ok, err := p.advance(indent); err != nil {
+			return nil, err
+		} else if !ok {
 			break
 		}
 
-		if lineIndent == indent {
-			trimmed := strings.TrimSpace(line)
-			if strings.HasPrefix(trimmed, "- ") {
-				itemValue := strings.TrimSpace(trimmed[2:])
-				if itemValue == "" {
-					// Multi-line item
-					p.pos++
-					parsedItem, err := p.parseValue(indent + p.opts.Indent)
-					if err != nil {
-						return nil, err
-					}
-					items = append(items, parsedItem)
-				} else {
-					// Single-line item
-					parsedItem, err := p.parsePrimitive(itemValue)
-					if err != nil {
-						return nil, err
-					}
-					items = append(items, parsedItem)
-					p.pos++
-				}
-			} else {
-				break
-			}
-		} else {
+		line := p.current()
+		lineIndent := utils.CountIndent(line)
+		trimmed := strings.TrimSpace(line)
+
+		if lineIndent != indent || !isListItem(trimmed) {
 			break
 		}
+
+		lineNo := p.pos + 1
+		item, err := p.parseListItem(strings.TrimSpace(trimmed[1:]), indent, lineIndent+3)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.addItem(items, item, lineNo); err != nil {
+			return nil, err
+		}
 	}
 
 	return items, nil
 }
 
-func (p *parser) parseDelimitedValues(line string) []string {
-	// Simple CSV-like parsing - can be enhanced for proper CSV parsing
-	var values []string
-	var current strings.Builder
-	inQuotes := false
-	
-	runes := []rune(line)
-	for i := 0; i < len(runes); i++ {
-		char := runes[i]
-		switch char {
-		case '"':
-			if inQuotes && i+1 < len(runes) && runes[i+1] == '"' {
-				// Escaped quote
-				current.WriteRune('"')
-				i++ // Skip next quote
-			} else {
-				inQuotes = !inQuotes
-			}
-		case ',':
-			if !inQuotes {
-				values = append(values, strings.TrimSpace(current.String()))
-				current.Reset()
-			} else {
-				current.WriteRune(char)
-			}
-		default:
-			current.WriteRune(char)
+// parseListItem parses the list item at the current line, whose content
+// after the hyphen is value. An object item carries its first field on the
+// hyphen line and the remaining fields one level deeper than the hyphen.
+func (p *parser) parseListItem(value string, indent, column int) (interface{}, error) {
+	fieldIndent := indent + p.opts.Indent
+
+	if value == "" {
+		p.pos++
+		if p.legacy() {
+			return p.parseLegacyValue(indent, nil)
+		}
+		// Legacy form: a bare hyphen followed by an indented object
+		if ok, err := p.advance(indent + 1); err != nil {
+			return nil, err
+		} else if ok {
+			return p.parseValue(fieldIndent, nil)
 		}
+		return types.NewOrderedObject(), nil
+	}
+
+	kl, ok, err := p.parseKeyLine(value, column)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		// Single-line item
+		item, err := p.parsePrimitive(value)
+		p.pos++
+		return item, err
+	}
+	if isArrayHeader(kl) {
+		// Nested array, with its rows or items one level below the hyphen
+		return p.parseArray(kl.header, indent, nil)
+	}
+
+	obj := types.NewOrderedObject()
+	if err := p.parseField(obj, kl, fieldIndent, column, nil); err != nil {
+		return nil, err
+	}
+	if err := p.parseFields(obj, fieldIndent, nil); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// isArrayHeader reports whether kl is an array header without a key, as
+// used by root arrays and arrays nested in lists ([N]:, [N]{f}:)
+func isArrayHeader(kl *keyLine) bool {
+	return kl.header != nil && kl.key == "" && !kl.quoted
+}
+
+// isListItem reports whether a trimmed line is a list item ("- x" or "-")
+func isListItem(trimmed string) bool {
+	return trimmed == "-" || strings.HasPrefix(trimmed, "- ")
+}
+
+// parseDelimitedValues splits a row on the delimiter declared by its array
+// header. Quoted values are returned with their quotes so that
+// parsePrimitive can tell "42" from 42.
+func (p *parser) parseDelimitedValues(line string, delimiter types.Delimiter) []string {
+	split := splitDelimited(line, delimiter)
+	values := make([]string, len(split))
+	for i, value := range split {
+		values[i] = value.text
 	}
-	
-	values = append(values, strings.TrimSpace(current.String()))
 	return values
 }
 
+// delimitedValue is a trimmed value of a row and its byte offset in the row
+type delimitedValue struct {
+	text   string
+	offset int
+}
+
+// splitDelimited splits line on delimiter outside quoted values
+func splitDelimited(line string, delimiter types.Delimiter) []delimitedValue {
+	var values []delimitedValue
+	start := 0
+	inQuotes := false
+
+	for i := 0; i < len(line); i++ {
+		char := line[i]
+		switch {
+		case char == '\\' && inQuotes && i+1 < len(line):
+			// Keep escape sequences intact for parsePrimitive
+			i++
+		case char == '"':
+			inQuotes = !inQuotes
+		case char == delimiter[0] && !inQuotes:
+			values = append(values, trimValue(line, start, i))
+			start = i + 1
+		}
+	}
+
+	return append(values, trimValue(line, start, len(line)))
+}
+
+func trimValue(line string, start, end int) delimitedValue {
+	raw := line[start:end]
+	text := strings.TrimSpace(raw)
+	return delimitedValue{text: text, offset: start + strings.Index(raw, text)}
+}
+
 func (p *parser) parsePrimitive(value string) (interface{}, error) {
 	value = strings.TrimSpace(value)
 
 	// Handle quoted strings
-	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
-		unquoted := value[1 : len(value)-1]
-		return strings.ReplaceAll(unquoted, `\"`, `"`), nil
+	if strings.HasPrefix(value, `"`) {
+		return p.parseQuotedString(value)
 	}
 
 	// Handle null
@@ -322,6 +706,16 @@ func (p *parser) parsePrimitive(value string) (interface{}, error) {
 		return nil, nil
 	}
 
+	// toonify 1.0 wrote empty arrays and objects as [] and {}
+	if p.legacy() {
+		switch value {
+		case "[]":
+			return []interface{}{}, nil
+		case "{}":
+			return types.NewOrderedObject(), nil
+		}
+	}
+
 	// Handle boolean
 	if value == "true" {
 		return true, nil
@@ -331,14 +725,68 @@ func (p *parser) parsePrimitive(value string) (interface{}, error) {
 	}
 
 	// Try to parse as number
-	if intVal, err := strconv.ParseInt(value, 10, 64); err == nil {
-		return intVal, nil
-	}
-
-	if floatVal, err := strconv.ParseFloat(value, 64); err == nil {
-		return floatVal, nil
+	if utils.IsNumber(value) {
+		if p.opts.UseNumber {
+			return types.Number(value), nil
+		}
+		if number, ok := utils.ParseNumber(value); ok {
+			return number, nil
+		}
 	}
 
 	// Return as string
 	return value, nil
 }
+
+// parseQuotedString unquotes a string token, resolving escape sequences.
+func (p *parser) parseQuotedString(value string) (string, error) {
+	column := p.columnOf(value)
+	if p.legacy() {
+		return p.parseLegacyQuotedString(value, column)
+	}
+
+	end := utils.FindClosingQuote(value, 0)
+	if end == -1 {
+		return "", types.NewToonError("unterminated string", p.pos+1, column)
+	}
+	if end != len(value)-1 {
+		return "", types.NewToonError(fmt.Sprintf("unexpected characters after string: %s", value[end+1:]), p.pos+1, column+end+1)
+	}
+
+	unescaped, offset, err := utils.Unescape(value[1:end], p.opts.Strict)
+	if err != nil {
+		return "", types.NewToonError(err.Error(), p.pos+1, column+1+offset)
+	}
+	return unescaped, nil
+}
+
+// parseLegacyQuotedString unquotes a string written by toonify 1.0, which
+// escaped double quotes and nothing else. A string ending in a backslash
+// looks like an escaped quote, so a value that has no other closing quote
+// ends at its last character.
+func (p *parser) parseLegacyQuotedString(value string, column int) (string, error) {
+	end := utils.FindLegacyClosingQuote(value, 0)
+	if end == -1 && len(value) > 1 && strings.HasSuffix(value, `"`) {
+		end = len(value) - 1
+	}
+	if end == -1 {
+		return "", types.NewToonError("unterminated string", p.pos+1, column)
+	}
+	if end != len(value)-1 {
+		return "", types.NewToonError(fmt.Sprintf("unexpected characters after string: %s", value[end+1:]), p.pos+1, column+end+1)
+	}
+	return utils.UnescapeLegacy(value[1:end]), nil
+}
+
+// columnOf returns the 1-based column at which token appears in the current
+// line, or 0 if it cannot be located.
+func (p *parser) columnOf(token string) int {
+	line, ok := p.src.line(p.pos)
+	if !ok {
+		return 0
+	}
+	if index := strings.Index(line, token); index != -1 {
+		return index + 1
+	}
+	return 0
+}
@@ -0,0 +1,98 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Palaciodiego008/toonify/internal/types"
+	"github.com/Palaciodiego008/toonify/internal/utils"
+)
+
+// source reads the lines of a document on demand. Only the lines from the
+// parser's position onwards are kept, so memory use does not grow with the
+// size of the input.
+type source struct {
+	r     *bufio.Reader
+	opts  *types.DecodeOptions
+	lines []string // lines read but not yet discarded, starting at base
+	base  int
+	eof   bool
+	err   error // read or indentation error that ended the input early
+}
+
+func newSource(r io.Reader, opts *types.DecodeOptions) *source {
+	return &source{r: bufio.NewReader(r), opts: opts}
+}
+
+// line returns the line at index i, reading up to it if needed. It reports
+// false past the end of input or once an error has stopped reading.
+func (s *source) line(i int) (string, bool) {
+	for i-s.base >= len(s.lines) {
+		if s.eof {
+			return "", false
+		}
+		s.read()
+	}
+	return s.lines[i-s.base], true
+}
+
+// discard drops the lines before index i, which the parser has moved past
+func (s *source) discard(i int) {
+	n := i - s.base
+	if n <= 0 {
+		return
+	}
+	if n > len(s.lines) {
+		n = len(s.lines)
+	}
+	for j := 0; j < n; j++ {
+		s.lines[j] = ""
+	}
+	s.lines = s.lines[n:]
+	s.base += n
+}
+
+// read appends the next line. In strict mode its indentation is checked as
+// it is read.
+func (s *source) read() {
+	line, err := s.r.ReadString('\n')
+	if err != nil {
+		s.eof = true
+		if err != io.EOF {
+			s.err = err
+			return
+		}
+		if line == "" {
+			return
+		}
+	}
+	line = strings.TrimSuffix(line, "\n")
+
+	if s.opts.Strict {
+		if err := s.validateIndentation(line, s.base+len(s.lines)+1); err != nil {
+			s.eof = true
+			s.err = err
+			return
+		}
+	}
+	s.lines = append(s.lines, line)
+}
+
+// validateIndentation checks that a non-blank line is indented with spaces
+// only, by a multiple of Indent. Legacy tab-delimited rows may start with a
+// tab separating an empty cell.
+func (s *source) validateIndentation(line string, lineNo int) error {
+	if strings.TrimSpace(line) == "" {
+		return nil
+	}
+	count := utils.CountIndent(line)
+	if line[count] == '\t' && s.opts.SpecVersion != types.SpecVersionLegacy {
+		return types.NewToonError("tab character in indentation", lineNo, count+1)
+	}
+	if count%s.opts.Indent != 0 {
+		return types.NewToonError(fmt.Sprintf("indentation of %d spaces is not a multiple of %d", count, s.opts.Indent), lineNo, count+1)
+	}
+	return nil
+}
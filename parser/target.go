@@ -0,0 +1,71 @@
+package parser
+
+import (
+	"github.com/Palaciodiego008/toonify/internal/types"
+	"github.com/Palaciodiego008/toonify/internal/utils"
+)
+
+// Target receives array items from the parser as they are read, so that a
+// large array can be decoded one item at a time instead of being collected
+// first. An array handed to a target is left out of the parsed value.
+type Target interface {
+	// Field returns the target for the value of key in an object, or nil to
+	// parse the value as usual.
+	Field(key string) Target
+	// Items returns a function that is called with each item of an array
+	// declaring length entries, or nil to collect the items as usual.
+	Items(length int) func(item interface{}) error
+}
+
+// arrayItems collects the entries of an array, or passes them on to the
+// function returned by a target.
+type arrayItems struct {
+	values []interface{}
+	emit   func(item interface{}) error
+	count  int
+}
+
+// newArrayItems prepares to read an array declaring length entries into t,
+// which may be nil.
+func (p *parser) newArrayItems(length int, t Target) *arrayItems {
+	items := &arrayItems{}
+	if t != nil {
+		items.emit = t.Items(length)
+	}
+	if items.emit == nil {
+		items.values = make([]interface{}, 0, utils.InitialCapacity(length))
+	}
+	return items
+}
+
+// addItem stores or emits item, read at line
+func (p *parser) addItem(items *arrayItems, item interface{}, line int) error {
+	items.count++
+	if items.emit == nil {
+		items.values = append(items.values, item)
+		return nil
+	}
+
+	if !p.opts.PreserveOrder {
+		item = toMaps(item)
+	}
+	if err := items.emit(item); err != nil {
+		// Conversion errors do not know where the item came from
+		if toonErr, ok := err.(*types.ToonError); ok && toonErr.Line == 0 {
+			return types.NewToonError(toonErr.Message, line, 1)
+		}
+		return err
+	}
+	return nil
+}
+
+// streamed stands in for an array that was handed to a target
+type streamed struct{}
+
+// value returns the collected items, or streamed{} if they were emitted
+func (items *arrayItems) value() interface{} {
+	if items.emit != nil {
+		return streamed{}
+	}
+	return items.values
+}
@@ -0,0 +1,435 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Palaciodiego008/toonify/internal/types"
+	"github.com/Palaciodiego008/toonify/internal/utils"
+)
+
+// TokenKind identifies the kind of a Token
+type TokenKind int
+
+// Token kinds. An object is ObjectStart, then a Key followed by its value
+// for each field, then ObjectEnd. An array is ArrayHeader, then its inline
+// values, its rows (RowStart, one Value per field, RowEnd) or its items
+// (ListItem followed by the item's value), then ArrayEnd.
+const (
+	TokenObjectStart TokenKind = iota + 1
+	TokenObjectEnd
+	TokenKey
+	TokenValue
+	TokenArrayHeader
+	TokenArrayEnd
+	TokenRowStart
+	TokenRowEnd
+	TokenListItem
+)
+
+var tokenKindNames = map[TokenKind]string{
+	TokenObjectStart: "ObjectStart",
+	TokenObjectEnd:   "ObjectEnd",
+	TokenKey:         "Key",
+	TokenValue:       "Value",
+	TokenArrayHeader: "ArrayHeader",
+	TokenArrayEnd:    "ArrayEnd",
+	TokenRowStart:    "RowStart",
+	TokenRowEnd:      "RowEnd",
+	TokenListItem:    "ListItem",
+}
+
+func (k TokenKind) String() string {
+	if name, ok := tokenKindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("TokenKind(%d)", int(k))
+}
+
+// Token is one syntactic element of a TOON document. Line and Column give
+// its 1-based position; end tokens carry the position of the token that
+// opened them.
+type Token struct {
+	Kind      TokenKind
+	Key       string          // TokenKey
+	Value     interface{}     // TokenValue: nil, bool, int64, float64, string or types.Number
+	Length    int             // TokenArrayHeader: declared length
+	Fields    []string        // TokenArrayHeader: field names of a tabular array
+	Delimiter types.Delimiter // TokenArrayHeader
+	Line      int
+	Column    int
+}
+
+type frameKind int
+
+const (
+	objectFrame frameKind = iota
+	listFrame
+	tableFrame
+)
+
+// frame is an object or array that is still open
+type frame struct {
+	kind   frameKind
+	indent int // indentation of the lines inside it
+	line   int // position of the token that opened it
+	column int
+	header *arrayHeader
+	count  int             // rows or items read so far
+	keys   map[string]bool // keys seen so far, in strict mode
+}
+
+// Tokenizer reads a TOON document as a stream of tokens, one line at a time.
+// It reads spec v2 and v3 syntax; legacy documents are rejected.
+type Tokenizer struct {
+	p       *parser
+	frames  []*frame
+	queue   []Token
+	started bool
+	done    bool // the root value was a primitive
+	err     error
+}
+
+// NewTokenizer returns a tokenizer that reads from r using opts, or the
+// default options if opts is nil.
+func NewTokenizer(r io.Reader, opts *types.DecodeOptions) *Tokenizer {
+	if opts == nil {
+		opts = types.DefaultDecodeOptions()
+	}
+	if opts.Indent <= 0 {
+		copied := *opts
+		copied.Indent = types.DefaultDecodeOptions().Indent
+		opts = &copied
+	}
+
+	t := &Tokenizer{p: &parser{src: newSource(r, opts), opts: opts}}
+	switch {
+	case !types.ValidSpecVersion(opts.SpecVersion):
+		t.err = types.NewToonError(fmt.Sprintf("unsupported spec version %q", opts.SpecVersion), 0, 0)
+	case opts.SpecVersion == types.SpecVersionLegacy:
+		t.err = types.NewToonError("the tokenizer does not read legacy syntax", 0, 0)
+	}
+	return t
+}
+
+// Token returns the next token. It returns io.EOF after the last token, and
+// the same error again on every later call once an error has occurred.
+func (t *Tokenizer) Token() (Token, error) {
+	for len(t.queue) == 0 {
+		if t.err != nil {
+			return Token{}, t.err
+		}
+		if err := t.readLine(); err != nil {
+			t.err = err
+			if err != io.EOF {
+				t.queue = nil
+			}
+		}
+	}
+
+	token := t.queue[0]
+	t.queue = t.queue[1:]
+	return token, nil
+}
+
+func (t *Tokenizer) emit(token Token) {
+	t.queue = append(t.queue, token)
+}
+
+// readLine queues the tokens of the next content line, or closes every
+// open value and returns io.EOF at the end of input.
+func (t *Tokenizer) readLine() error {
+	p := t.p
+	p.src.discard(p.pos)
+
+	next := p.nextContentLine()
+	line, ok := p.src.line(next)
+	if !ok {
+		if p.src.err != nil {
+			return p.src.err
+		}
+		if !t.started {
+			// An empty document is an empty object
+			t.started = true
+			t.emit(Token{Kind: TokenObjectStart, Line: 1, Column: 1})
+			t.emit(Token{Kind: TokenObjectEnd, Line: 1, Column: 1})
+			return io.EOF
+		}
+		for len(t.frames) > 0 {
+			if err := t.closeFrame(); err != nil {
+				return err
+			}
+		}
+		return io.EOF
+	}
+
+	blankLine := p.pos + 1
+	skippedBlank := next > p.pos
+	p.pos = next
+	err := t.readContent(line, skippedBlank, blankLine)
+	p.pos++
+	return err
+}
+
+// readContent queues the tokens of line, the content line at the current
+// position. blankLine is the first of any blank lines skipped before it.
+func (t *Tokenizer) readContent(line string, skippedBlank bool, blankLine int) error {
+	p := t.p
+	lineNo := p.pos + 1
+	lineIndent := utils.CountIndent(line)
+	trimmed := strings.TrimSpace(line)
+
+	if !t.started {
+		t.started = true
+		return t.readRoot(trimmed, lineIndent)
+	}
+	if t.done {
+		return types.NewToonError("unexpected content after root value", lineNo, lineIndent+1)
+	}
+
+	// Close the values this line is outside of
+	for len(t.frames) > 0 && lineIndent < t.top().indent {
+		if err := t.closeFrame(); err != nil {
+			return err
+		}
+	}
+	if len(t.frames) == 0 {
+		return types.NewToonError("unexpected content after root array", lineNo, lineIndent+1)
+	}
+	if skippedBlank && p.opts.Strict && t.inArray() {
+		return types.NewToonError("blank line inside array", blankLine, 1)
+	}
+
+	f := t.top()
+	if lineIndent > f.indent {
+		return types.NewToonError(fmt.Sprintf("unexpected indentation at line %d", lineNo), lineNo, lineIndent+1)
+	}
+
+	switch f.kind {
+	case tableFrame:
+		return t.readRow(f, line, lineIndent)
+	case listFrame:
+		if !isListItem(trimmed) {
+			return types.NewToonError(fmt.Sprintf("expected list item: %s", trimmed), lineNo, lineIndent+1)
+		}
+		return t.readListItem(f, trimmed, lineIndent)
+	default:
+		kl, ok, err := p.parseKeyLine(trimmed, lineIndent+1)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return types.NewToonError(fmt.Sprintf("expected key-value pair: %s", trimmed), lineNo, lineIndent+1)
+		}
+		return t.readField(f, kl, lineIndent, lineIndent+1)
+	}
+}
+
+// readRoot queues the tokens of the first content line, which decides
+// whether the document is an object, an array or a single primitive.
+func (t *Tokenizer) readRoot(trimmed string, lineIndent int) error {
+	lineNo := t.p.pos + 1
+	if lineIndent > 0 {
+		return types.NewToonError(fmt.Sprintf("unexpected indentation at line %d", lineNo), lineNo, lineIndent+1)
+	}
+	if isListItem(trimmed) {
+		return types.NewToonError("list item outside an array", lineNo, 1)
+	}
+
+	kl, ok, err := t.p.parseKeyLine(trimmed, 1)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		t.done = true
+		return t.readValue(trimmed, 1)
+	}
+	if isArrayHeader(kl) {
+		return t.readArray(kl.header, 0)
+	}
+
+	f := t.openObject(0, lineNo, 1)
+	return t.readField(f, kl, 0, 1)
+}
+
+// readField queues the tokens of the key line kl in the object f. depth is
+// the indentation of the field and column the position of its key.
+func (t *Tokenizer) readField(f *frame, kl *keyLine, depth, column int) error {
+	lineNo := t.p.pos + 1
+	if f.keys != nil {
+		if f.keys[kl.key] {
+			return types.NewToonError(fmt.Sprintf("duplicate key %q", kl.key), lineNo, column)
+		}
+		f.keys[kl.key] = true
+	}
+	t.emit(Token{Kind: TokenKey, Key: kl.key, Line: lineNo, Column: column})
+
+	switch {
+	case kl.header != nil:
+		return t.readArray(kl.header, depth)
+	case kl.value == "":
+		// A nested object, empty if nothing is indented below the key
+		t.openObject(depth+t.p.opts.Indent, lineNo, column)
+		return nil
+	default:
+		return t.readValue(kl.value, t.valueColumn(kl.value))
+	}
+}
+
+// readArray queues the header of an array at depth. Inline arrays are
+// complete; other arrays stay open for their rows or items.
+func (t *Tokenizer) readArray(header *arrayHeader, depth int) error {
+	t.emit(Token{
+		Kind:      TokenArrayHeader,
+		Length:    header.length,
+		Fields:    header.fields,
+		Delimiter: header.delimiter,
+		Line:      header.line,
+		Column:    header.column,
+	})
+
+	kind := listFrame
+	if header.fields != nil {
+		kind = tableFrame
+	}
+	f := &frame{kind: kind, indent: depth + t.p.opts.Indent, line: header.line, column: header.column, header: header}
+
+	if header.fields == nil && (header.inline != "" || header.length == 0) {
+		if header.inline != "" {
+			start := t.valueColumn(header.inline)
+			for _, value := range splitDelimited(header.inline, header.delimiter) {
+				if err := t.readValue(value.text, start+value.offset); err != nil {
+					return err
+				}
+				f.count++
+			}
+		}
+		return t.endArray(f)
+	}
+
+	t.frames = append(t.frames, f)
+	return nil
+}
+
+// readRow queues a row of the tabular array f
+func (t *Tokenizer) readRow(f *frame, line string, lineIndent int) error {
+	lineNo := t.p.pos + 1
+	trimmed := strings.TrimSpace(line)
+	values := splitDelimited(trimmed, f.header.delimiter)
+	if len(values) != len(f.header.fields) {
+		return types.NewToonError(fmt.Sprintf("field count mismatch at line %d: expected %d, got %d", lineNo, len(f.header.fields), len(values)), lineNo, lineIndent+1)
+	}
+
+	start := strings.Index(line, trimmed) + 1
+	t.emit(Token{Kind: TokenRowStart, Line: lineNo, Column: start})
+	for _, value := range values {
+		if err := t.readValue(value.text, start+value.offset); err != nil {
+			return err
+		}
+	}
+	t.emit(Token{Kind: TokenRowEnd, Line: lineNo, Column: start})
+	f.count++
+	return nil
+}
+
+// readListItem queues an item of the list array f. An object item carries
+// its first field on the hyphen line.
+func (t *Tokenizer) readListItem(f *frame, trimmed string, lineIndent int) error {
+	lineNo := t.p.pos + 1
+	f.count++
+	t.emit(Token{Kind: TokenListItem, Line: lineNo, Column: lineIndent + 1})
+
+	content := strings.TrimSpace(trimmed[1:])
+	column := lineIndent + 3
+	if content == "" {
+		// An empty object, or the fields of one indented below the hyphen
+		t.openObject(lineIndent+t.p.opts.Indent, lineNo, lineIndent+1)
+		return nil
+	}
+
+	kl, ok, err := t.p.parseKeyLine(content, column)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return t.readValue(content, column)
+	}
+	if isArrayHeader(kl) {
+		return t.readArray(kl.header, lineIndent)
+	}
+
+	obj := t.openObject(lineIndent+t.p.opts.Indent, lineNo, column)
+	return t.readField(obj, kl, lineIndent+t.p.opts.Indent, column)
+}
+
+// readValue queues a primitive value found at column of the current line
+func (t *Tokenizer) readValue(text string, column int) error {
+	value, err := t.p.parsePrimitive(text)
+	if err != nil {
+		return err
+	}
+	t.emit(Token{Kind: TokenValue, Value: value, Line: t.p.pos + 1, Column: column})
+	return nil
+}
+
+// valueColumn returns the column of text at the end of the current line
+func (t *Tokenizer) valueColumn(text string) int {
+	line := strings.TrimRight(t.p.current(), " \t\r")
+	return len(line) - len(text) + 1
+}
+
+func (t *Tokenizer) openObject(indent, line, column int) *frame {
+	f := &frame{kind: objectFrame, indent: indent, line: line, column: column}
+	if t.p.opts.Strict {
+		f.keys = map[string]bool{}
+	}
+	t.emit(Token{Kind: TokenObjectStart, Line: line, Column: column})
+	t.frames = append(t.frames, f)
+	return f
+}
+
+func (t *Tokenizer) top() *frame {
+	return t.frames[len(t.frames)-1]
+}
+
+// inArray reports whether any open value is an array
+func (t *Tokenizer) inArray() bool {
+	for _, f := range t.frames {
+		if f.kind != objectFrame {
+			return true
+		}
+	}
+	return false
+}
+
+// closeFrame ends the innermost open value
+func (t *Tokenizer) closeFrame() error {
+	f := t.top()
+	t.frames = t.frames[:len(t.frames)-1]
+	if f.kind == objectFrame {
+		t.emit(Token{Kind: TokenObjectEnd, Line: f.line, Column: f.column})
+		return nil
+	}
+	return t.endArray(f)
+}
+
+// endArray queues the end of the array f, checking its length in strict
+// mode
+func (t *Tokenizer) endArray(f *frame) error {
+	if f.kind == tableFrame {
+		if err := t.p.checkLength(f.header, f.count, "rows"); err != nil {
+			return err
+		}
+	} else {
+		noun := "items"
+		if f.header.inline != "" || f.header.length == 0 {
+			noun = "values"
+		}
+		if err := t.p.checkLength(f.header, f.count, noun); err != nil {
+			return err
+		}
+	}
+	t.emit(Token{Kind: TokenArrayEnd, Line: f.line, Column: f.column})
+	return nil
+}
@@ -0,0 +1,54 @@
+package toonify
+
+import (
+	"io"
+
+	"github.com/Palaciodiego008/toonify/decoder"
+	"github.com/Palaciodiego008/toonify/encoder"
+)
+
+// An Encoder writes TOON documents to an output stream.
+type Encoder struct {
+	w    io.Writer
+	opts *EncodeOptions
+}
+
+// NewEncoder returns an encoder that writes to w using the default options.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// SetOptions sets the options used by later calls to Encode.
+func (e *Encoder) SetOptions(opts *EncodeOptions) {
+	e.opts = opts
+}
+
+// Encode writes the TOON encoding of v to the stream, followed by a newline.
+// Lines are written as they are produced rather than built up in memory, so
+// large slices can be exported with bounded memory use.
+func (e *Encoder) Encode(v interface{}) error {
+	return encoder.New(e.opts).EncodeTo(e.w, v)
+}
+
+// A Decoder reads a TOON document from an input stream.
+type Decoder struct {
+	r    io.Reader
+	opts *DecodeOptions
+}
+
+// NewDecoder returns a decoder that reads from r using the default options.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// SetOptions sets the options used by later calls to Decode.
+func (d *Decoder) SetOptions(opts *DecodeOptions) {
+	d.opts = opts
+}
+
+// Decode reads the rest of the stream as one TOON document and stores it in
+// v. Lines are read as they are needed, and tabular and list arrays that map
+// onto slices in v are decoded into them row by row.
+func (d *Decoder) Decode(v interface{}) error {
+	return decoder.New(d.opts).DecodeFrom(d.r, v)
+}
@@ -0,0 +1,43 @@
+// Package toon provides low-level streaming access to TOON documents. A
+// Tokenizer reads a document as a stream of tokens, so that filters,
+// validators and transcoders can process it without building Go values.
+package toon
+
+import (
+	"io"
+
+	"github.com/Palaciodiego008/toonify/internal/types"
+	"github.com/Palaciodiego008/toonify/parser"
+)
+
+// Token is one syntactic element of a TOON document, with its position.
+type Token = parser.Token
+
+// Kind identifies the kind of a Token.
+type Kind = parser.TokenKind
+
+// Token kinds. An object is ObjectStart, then a Key followed by its value
+// for each field, then ObjectEnd. An array is ArrayHeader, then its inline
+// values, its rows (RowStart, one Value per field, RowEnd) or its items
+// (ListItem followed by the item's value), then ArrayEnd.
+const (
+	ObjectStart = parser.TokenObjectStart
+	ObjectEnd   = parser.TokenObjectEnd
+	Key         = parser.TokenKey
+	Value       = parser.TokenValue
+	ArrayHeader = parser.TokenArrayHeader
+	ArrayEnd    = parser.TokenArrayEnd
+	RowStart    = parser.TokenRowStart
+	RowEnd      = parser.TokenRowEnd
+	ListItem    = parser.TokenListItem
+)
+
+// Tokenizer reads TOON tokens from an input stream.
+type Tokenizer = parser.Tokenizer
+
+// NewTokenizer returns a tokenizer that reads from r. A nil opts uses the
+// default decode options; Strict enables the same length, indentation,
+// blank line and duplicate key checks as decoding.
+func NewTokenizer(r io.Reader, opts *types.DecodeOptions) *Tokenizer {
+	return parser.NewTokenizer(r, opts)
+}
@@ -0,0 +1,163 @@
+package toon
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Palaciodiego008/toonify/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// describe reads every token of input as a compact string
+func describe(t *testing.T, input string) []string {
+	t.Helper()
+
+	var tokens []string
+	tk := NewTokenizer(strings.NewReader(input), nil)
+	for {
+		token, err := tk.Token()
+		if err == io.EOF {
+			return tokens
+		}
+		require.NoError(t, err)
+
+		switch token.Kind {
+		case Key:
+			tokens = append(tokens, "Key "+token.Key)
+		case Value:
+			tokens = append(tokens, fmt.Sprintf("Value %#v", token.Value))
+		case ArrayHeader:
+			tokens = append(tokens, fmt.Sprintf("ArrayHeader %d %v %q", token.Length, token.Fields, token.Delimiter))
+		default:
+			tokens = append(tokens, token.Kind.String())
+		}
+	}
+}
+
+func TestTokenizer(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			"object",
+			"name: Alice\nmeta:\n  age: 30\nempty:",
+			[]string{
+				"ObjectStart", "Key name", `Value "Alice"`,
+				"Key meta", "ObjectStart", "Key age", "Value 30", "ObjectEnd",
+				"Key empty", "ObjectStart", "ObjectEnd",
+				"ObjectEnd",
+			},
+		},
+		{
+			"inline array",
+			"tags[3|]: a|\"b|c\"|null",
+			[]string{
+				"ObjectStart", "Key tags", `ArrayHeader 3 [] "|"`,
+				`Value "a"`, `Value "b|c"`, "Value <nil>", "ArrayEnd", "ObjectEnd",
+			},
+		},
+		{
+			"tabular array",
+			"users[2]{id,name}:\n  1,Alice\n  2,Bob\ncount: 2",
+			[]string{
+				"ObjectStart", "Key users", `ArrayHeader 2 [id name] ","`,
+				"RowStart", "Value 1", `Value "Alice"`, "RowEnd",
+				"RowStart", "Value 2", `Value "Bob"`, "RowEnd",
+				"ArrayEnd", "Key count", "Value 2", "ObjectEnd",
+			},
+		},
+		{
+			"list items",
+			"items[3]:\n  - 1\n  - id: 2\n    tags[1]: x\n  - [2]: a,b",
+			[]string{
+				"ObjectStart", "Key items", `ArrayHeader 3 [] ","`,
+				"ListItem", "Value 1",
+				"ListItem", "ObjectStart", "Key id", "Value 2",
+				"Key tags", `ArrayHeader 1 [] ","`, `Value "x"`, "ArrayEnd", "ObjectEnd",
+				"ListItem", `ArrayHeader 2 [] ","`, `Value "a"`, `Value "b"`, "ArrayEnd",
+				"ArrayEnd", "ObjectEnd",
+			},
+		},
+		{
+			"root array",
+			"[1]{a}:\n  true",
+			[]string{`ArrayHeader 1 [a] ","`, "RowStart", "Value true", "RowEnd", "ArrayEnd"},
+		},
+		{
+			"root primitive",
+			"3.5",
+			[]string{"Value 3.5"},
+		},
+		{
+			"empty document",
+			"\n",
+			[]string{"ObjectStart", "ObjectEnd"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, describe(t, tt.input))
+		})
+	}
+}
+
+func TestTokenizerPositions(t *testing.T) {
+	tk := NewTokenizer(strings.NewReader("a:\n  rows[1]{x,y}:\n    1, \"q\""), nil)
+
+	var positions []string
+	for {
+		token, err := tk.Token()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		positions = append(positions, fmt.Sprintf("%v %d:%d", token.Kind, token.Line, token.Column))
+	}
+
+	assert.Equal(t, []string{
+		"ObjectStart 1:1", "Key 1:1", "ObjectStart 1:1",
+		"Key 2:3", "ArrayHeader 2:7", "RowStart 3:5", "Value 3:5", "Value 3:8", "RowEnd 3:5", "ArrayEnd 2:7",
+		"ObjectEnd 1:1", "ObjectEnd 1:1",
+	}, positions)
+}
+
+func TestTokenizerErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		line   int
+		column int
+	}{
+		{"length mismatch", "tags[3]: a,b", 1, 5},
+		{"field count", "rows[1]{a,b}:\n  1", 2, 3},
+		{"indentation", "a: 1\n    b: 2", 2, 5},
+		{"blank line in array", "rows[2]{a}:\n  1\n\n  2", 3, 1},
+		{"duplicate key", "a: 1\na: 2", 2, 1},
+		{"not a field", "a: 1\nb", 2, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tk := NewTokenizer(strings.NewReader(tt.input), nil)
+			var err error
+			for err == nil {
+				_, err = tk.Token()
+			}
+
+			var toonErr *types.ToonError
+			require.ErrorAs(t, err, &toonErr)
+			assert.Equal(t, tt.line, toonErr.Line)
+			assert.Equal(t, tt.column, toonErr.Column)
+
+			// The error is sticky
+			_, again := tk.Token()
+			assert.Equal(t, err, again)
+		})
+	}
+}
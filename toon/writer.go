@@ -0,0 +1,19 @@
+package toon
+
+import (
+	"io"
+
+	"github.com/Palaciodiego008/toonify/encoder"
+	"github.com/Palaciodiego008/toonify/internal/types"
+)
+
+// Writer writes a TOON document incrementally, without building Go values
+// first.
+type Writer = encoder.Writer
+
+// NewWriter returns a writer that writes to w. A nil opts uses the default
+// encode options; Indent and Delimiter set the layout of the output. Call
+// Flush once the document is complete.
+func NewWriter(w io.Writer, opts *types.EncodeOptions) *Writer {
+	return encoder.NewWriter(w, opts)
+}
@@ -0,0 +1,145 @@
+package toon
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Palaciodiego008/toonify/encoder"
+	"github.com/Palaciodiego008/toonify/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, nil)
+
+	require.NoError(t, w.BeginObject())
+	require.NoError(t, w.Key("name"))
+	require.NoError(t, w.String("a, b"))
+	require.NoError(t, w.Key("meta"))
+	require.NoError(t, w.BeginObject())
+	require.NoError(t, w.Key("version"))
+	require.NoError(t, w.Float(1.5))
+	require.NoError(t, w.End())
+
+	require.NoError(t, w.BeginTable("users", 2, []string{"id", "name"}))
+	require.NoError(t, w.Row(1, "Alice"))
+	require.NoError(t, w.Row(2, nil))
+	require.NoError(t, w.End())
+
+	require.NoError(t, w.Key("items"))
+	require.NoError(t, w.BeginList("", 4))
+	require.NoError(t, w.Int(1))
+	require.NoError(t, w.BeginObject())
+	require.NoError(t, w.Key("id"))
+	require.NoError(t, w.Int(2))
+	require.NoError(t, w.Key("ok"))
+	require.NoError(t, w.Bool(true))
+	require.NoError(t, w.End())
+	require.NoError(t, w.BeginObject())
+	require.NoError(t, w.End())
+	require.NoError(t, w.BeginList("", 1))
+	require.NoError(t, w.Null())
+	require.NoError(t, w.End())
+	require.NoError(t, w.End())
+	require.NoError(t, w.End())
+	require.NoError(t, w.Flush())
+
+	expected := `name: "a, b"
+meta:
+  version: 1.5
+users[2]{id,name}:
+  1,Alice
+  2,null
+items[4]:
+  - 1
+  - id: 2
+    ok: true
+  -
+  - [1]:
+    - null
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestWriterMatchesEncoder(t *testing.T) {
+	opts := types.DefaultEncodeOptions()
+	opts.Delimiter = types.DelimiterPipe
+	opts.Indent = 4
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf, opts)
+	require.NoError(t, w.BeginList("", 1))
+	require.NoError(t, w.BeginObject())
+	require.NoError(t, w.BeginTable("rows", 2, []string{"a", "b c"}))
+	require.NoError(t, w.Row("x|y", 1))
+	require.NoError(t, w.Row("z", 2.0))
+	require.NoError(t, w.End())
+	require.NoError(t, w.Key("n"))
+	require.NoError(t, w.Int(3))
+	require.NoError(t, w.End())
+	require.NoError(t, w.End())
+	require.NoError(t, w.Flush())
+
+	value := []interface{}{
+		map[string]interface{}{
+			"rows": []map[string]interface{}{{"a": "x|y", "b c": 1}, {"a": "z", "b c": 2.0}},
+			"n":    3,
+		},
+	}
+	opts.PriorityKeys = []string{"rows"}
+	expected, err := encoder.New(opts).Encode(value)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected)+"\n", buf.String())
+}
+
+func TestWriterErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(w *Writer) error
+	}{
+		{"value without key", func(w *Writer) error {
+			w.BeginObject()
+			return w.String("x")
+		}},
+		{"key outside object", func(w *Writer) error {
+			return w.Key("a")
+		}},
+		{"too many rows", func(w *Writer) error {
+			w.BeginTable("", 1, []string{"a"})
+			w.Row(1)
+			return w.Row(2)
+		}},
+		{"too few items", func(w *Writer) error {
+			w.BeginList("", 2)
+			w.Int(1)
+			return w.End()
+		}},
+		{"wrong row width", func(w *Writer) error {
+			w.BeginTable("", 1, []string{"a", "b"})
+			return w.Row(1)
+		}},
+		{"complex row value", func(w *Writer) error {
+			w.BeginTable("", 1, []string{"a"})
+			return w.Row([]int{1})
+		}},
+		{"second root", func(w *Writer) error {
+			w.Int(1)
+			return w.Int(2)
+		}},
+		{"keyed array in list", func(w *Writer) error {
+			w.BeginList("", 1)
+			return w.BeginList("a", 0)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewWriter(&bytes.Buffer{}, nil)
+			err := tt.write(w)
+			require.Error(t, err)
+			assert.Equal(t, err, w.Flush())
+		})
+	}
+}
@@ -2,14 +2,99 @@
 package toonify
 
 import (
-	"github.com/Palaciodiego008/toonify/encoder"
 	"github.com/Palaciodiego008/toonify/decoder"
+	"github.com/Palaciodiego008/toonify/encoder"
 	"github.com/Palaciodiego008/toonify/internal/types"
 )
 
+// EncodeOptions configures TOON encoding behavior.
+type EncodeOptions = types.EncodeOptions
+
+// DecodeOptions configures TOON decoding behavior.
+type DecodeOptions = types.DecodeOptions
+
+// Delimiter separates values in tabular and inline arrays.
+type Delimiter = types.Delimiter
+
+// OrderedObject is a TOON object that keeps its keys in document order. It
+// is produced when DecodeOptions.PreserveOrder is set, and the encoder
+// writes it back in the same order.
+type OrderedObject = types.OrderedObject
+
+// Marshaler is implemented by types that encode themselves to TOON, at any
+// level of a value including tabular rows. MarshalTOON returns TOON text,
+// such as a single primitive or an object.
+type Marshaler = types.Marshaler
+
+// Unmarshaler is implemented by types that decode their own TOON
+// representation. UnmarshalTOON receives the TOON text of the value.
+type Unmarshaler = types.Unmarshaler
+
+// Number is a number literal kept digit for digit. Decoding produces it
+// instead of int64 and float64 when DecodeOptions.UseNumber is set.
+type Number = types.Number
+
+// ToonError reports a TOON processing error with its position.
+type ToonError = types.ToonError
+
+// Supported delimiters.
+const (
+	DelimiterComma = types.DelimiterComma
+	DelimiterTab   = types.DelimiterTab
+	DelimiterPipe  = types.DelimiterPipe
+)
+
+// Key folding and path expansion modes.
+const (
+	KeyFoldingOff   = types.KeyFoldingOff
+	KeyFoldingSafe  = types.KeyFoldingSafe
+	ExpandPathsOff  = types.ExpandPathsOff
+	ExpandPathsSafe = types.ExpandPathsSafe
+)
+
+// TOON spec versions. SpecVersionLegacy reads and writes the syntax of
+// toonify 1.0 so that documents stored by older builds can still be decoded.
+const (
+	SpecVersionLegacy = types.SpecVersionLegacy
+	SpecVersion2      = types.SpecVersion2
+	SpecVersion3      = types.SpecVersion3
+)
+
+// DefaultEncodeOptions returns the default encoding options.
+func DefaultEncodeOptions() *EncodeOptions {
+	return types.DefaultEncodeOptions()
+}
+
+// DefaultDecodeOptions returns the default decoding options.
+func DefaultDecodeOptions() *DecodeOptions {
+	return types.DefaultDecodeOptions()
+}
+
+// NewOrderedObject creates an empty ordered object.
+func NewOrderedObject() *OrderedObject {
+	return types.NewOrderedObject()
+}
+
+// UnmarshalOrderedJSON parses JSON into generic values, using
+// *OrderedObject for objects so that key order survives a later Encode.
+func UnmarshalOrderedJSON(data []byte) (interface{}, error) {
+	return types.UnmarshalOrderedJSON(data)
+}
+
+// UnmarshalOrderedJSONNumbers is like UnmarshalOrderedJSON, but reads
+// numbers as Number values so that large integers and long decimals are
+// encoded exactly.
+func UnmarshalOrderedJSONNumbers(data []byte) (interface{}, error) {
+	return types.UnmarshalOrderedJSONNumbers(data)
+}
+
 // Encode converts Go data to TOON format.
 func Encode(v interface{}) (string, error) {
-	opts := types.DefaultEncodeOptions()
+	return EncodeWithOptions(v, nil)
+}
+
+// EncodeWithOptions converts Go data to TOON format using opts.
+func EncodeWithOptions(v interface{}, opts *EncodeOptions) (string, error) {
 	enc := encoder.New(opts)
 	data, err := enc.Encode(v)
 	if err != nil {
@@ -20,7 +105,11 @@ func Encode(v interface{}) (string, error) {
 
 // Decode converts TOON format to Go data.
 func Decode(data string, v interface{}) error {
-	opts := types.DefaultDecodeOptions()
+	return DecodeWithOptions(data, v, nil)
+}
+
+// DecodeWithOptions converts TOON format to Go data using opts.
+func DecodeWithOptions(data string, v interface{}, opts *DecodeOptions) error {
 	dec := decoder.New(opts)
 	return dec.Decode([]byte(data), v)
 }
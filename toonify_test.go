@@ -1,7 +1,11 @@
 package toonify
 
 import (
+	"bytes"
+	"encoding/json"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -66,3 +70,308 @@ func TestEncodeDecodeBytes(t *testing.T) {
 	assert.Equal(t, "value", decoded["test"])
 	assert.Equal(t, int64(42), decoded["num"])
 }
+
+func TestKeyFoldingRoundtrip(t *testing.T) {
+	data := map[string]interface{}{
+		"server": map[string]interface{}{
+			"http": map[string]interface{}{"port": 8080},
+		},
+	}
+
+	encodeOpts := DefaultEncodeOptions()
+	encodeOpts.KeyFolding = KeyFoldingSafe
+	encoded, err := EncodeWithOptions(data, encodeOpts)
+	require.NoError(t, err)
+	assert.Equal(t, "server.http.port: 8080", encoded)
+
+	decodeOpts := DefaultDecodeOptions()
+	decodeOpts.ExpandPaths = ExpandPathsSafe
+	var decoded map[string]interface{}
+	require.NoError(t, DecodeWithOptions(encoded, &decoded, decodeOpts))
+	assert.Equal(t, map[string]interface{}{
+		"server": map[string]interface{}{
+			"http": map[string]interface{}{"port": int64(8080)},
+		},
+	}, decoded)
+}
+
+func TestStringRoundtrip(t *testing.T) {
+	values := []string{
+		"", " padded ", "null", "3.14", "-", "- item", "[1]", "{x}",
+		"a:b", `say "hi"`, `C:\path`, "multi\nline", "tab\there", "a,b", "plain text",
+	}
+
+	for _, delimiter := range []Delimiter{DelimiterComma, DelimiterTab, DelimiterPipe} {
+		opts := DefaultEncodeOptions()
+		opts.Delimiter = delimiter
+
+		data := map[string]interface{}{"list": values}
+		for i, v := range values {
+			data[string(rune('a'+i))] = v
+		}
+
+		encoded, err := EncodeWithOptions(data, opts)
+		require.NoError(t, err)
+
+		var decoded map[string]interface{}
+		require.NoError(t, Decode(encoded, &decoded))
+
+		for i, v := range values {
+			assert.Equal(t, v, decoded[string(rune('a'+i))])
+		}
+		list := make([]interface{}, len(values))
+		for i, v := range values {
+			list[i] = v
+		}
+		assert.Equal(t, list, decoded["list"])
+	}
+}
+
+func TestRootArrayRoundtrip(t *testing.T) {
+	type user struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+
+	users := []user{{1, "Alice"}, {2, "Bob"}}
+	encoded, err := Encode(users)
+	require.NoError(t, err)
+	assert.Equal(t, "[2]{id,name}:\n  1,Alice\n  2,Bob", encoded)
+
+	var decodedUsers []user
+	require.NoError(t, Decode(encoded, &decodedUsers))
+	assert.Equal(t, users, decodedUsers)
+
+	matrix := [][]float64{{1, 2.5}, {}, {-3}}
+	encoded, err = Encode(matrix)
+	require.NoError(t, err)
+
+	var decodedMatrix [][]float64
+	require.NoError(t, Decode(encoded, &decodedMatrix))
+	assert.Equal(t, matrix, decodedMatrix)
+}
+
+func TestTimeDurationBytesRoundtrip(t *testing.T) {
+	type job struct {
+		Name    string        `json:"name"`
+		Started time.Time     `json:"started"`
+		Due     time.Time     `toon:"due,format=2006-01-02"`
+		Timeout time.Duration `json:"timeout"`
+		Key     []byte        `json:"key"`
+	}
+
+	zone := time.FixedZone("", -5*3600)
+	jobs := []job{
+		{"a", time.Date(2025, 1, 2, 3, 4, 5, 6, zone), time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), 2 * time.Hour, []byte{0, 1, 254, 255}},
+		{"b", time.Date(2025, 6, 7, 8, 9, 10, 0, time.UTC), time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC), 1500 * time.Millisecond, nil},
+	}
+	encoded, err := Encode(jobs)
+	require.NoError(t, err)
+
+	var decoded []job
+	require.NoError(t, Decode(encoded, &decoded))
+	require.Len(t, decoded, len(jobs))
+	for i := range jobs {
+		assert.True(t, jobs[i].Started.Equal(decoded[i].Started), encoded)
+		decoded[i].Started = jobs[i].Started
+	}
+	assert.Equal(t, jobs, decoded)
+}
+
+func TestUseNumberRoundtrip(t *testing.T) {
+	input := `{"id":123456789012345678901234567890,"amounts":[0.1000000000000000055511151231257827,-2.5e-3]}`
+	data, err := UnmarshalOrderedJSONNumbers([]byte(input))
+	require.NoError(t, err)
+
+	encoded, err := Encode(data)
+	require.NoError(t, err)
+	assert.Equal(t, "id: 123456789012345678901234567890\namounts[2]: 0.1000000000000000055511151231257827,-0.0025", encoded)
+
+	opts := DefaultDecodeOptions()
+	opts.UseNumber = true
+	opts.PreserveOrder = true
+	var decoded interface{}
+	require.NoError(t, DecodeWithOptions(encoded, &decoded, opts))
+
+	jsonData, err := json.Marshal(decoded)
+	require.NoError(t, err)
+	assert.Equal(t, `{"id":123456789012345678901234567890,"amounts":[0.1000000000000000055511151231257827,-0.0025]}`, string(jsonData))
+}
+
+func TestLegacySpecRoundtrip(t *testing.T) {
+	data := map[string]interface{}{
+		"rows":  []interface{}{map[string]interface{}{"id": 1, "note": nil}, map[string]interface{}{"id": 2, "note": "x"}},
+		"tags":  []interface{}{"a", "b"},
+		"items": []interface{}{map[string]interface{}{"a": 1}, []interface{}{}, "z"},
+		"empty": map[string]interface{}{},
+	}
+
+	encodeOpts := DefaultEncodeOptions()
+	encodeOpts.SpecVersion = SpecVersionLegacy
+	encodeOpts.Delimiter = DelimiterTab
+	encoded, err := EncodeWithOptions(data, encodeOpts)
+	require.NoError(t, err)
+
+	decodeOpts := DefaultDecodeOptions()
+	decodeOpts.SpecVersion = SpecVersionLegacy
+	var decoded map[string]interface{}
+	require.NoError(t, DecodeWithOptions(encoded, &decoded, decodeOpts))
+
+	expected, err := json.Marshal(data)
+	require.NoError(t, err)
+	actual, err := json.Marshal(decoded)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expected), string(actual))
+}
+
+func TestLegacySpecBaselineOutput(t *testing.T) {
+	// Written by toonify 1.0 for a map of these values. It wrote backslashes
+	// as they are and left a row blank when all of its cells were null.
+	baseline := "path: \"C:\\path\"\nnew: \"C:\\new\"\nquote: \"say \\\"hi\\\"\"\nmixed: \"a\\\\\"b\"\n" +
+		"notes:\n  [3]{note}:\n    \n    x\n    \n" +
+		"files:\n  [2]{path,label}:\n    \"D:\\tmp\",\n    \"E:\\a,b\",z\n" +
+		"nested:\n    dir: \\\\server\\share"
+	expected := map[string]interface{}{
+		"path":  `C:\path`,
+		"new":   `C:\new`,
+		"quote": `say "hi"`,
+		"mixed": `a\"b`,
+		"notes": []interface{}{
+			map[string]interface{}{"note": nil},
+			map[string]interface{}{"note": "x"},
+			map[string]interface{}{"note": nil},
+		},
+		"files": []interface{}{
+			map[string]interface{}{"path": `D:\tmp`, "label": nil},
+			map[string]interface{}{"path": `E:\a,b`, "label": "z"},
+		},
+		"nested": map[string]interface{}{"dir": `\\server\share`},
+	}
+
+	for _, strict := range []bool{true, false} {
+		opts := DefaultDecodeOptions()
+		opts.SpecVersion = SpecVersionLegacy
+		opts.Strict = strict
+
+		var decoded map[string]interface{}
+		require.NoError(t, DecodeWithOptions(baseline, &decoded, opts))
+		assert.Equal(t, expected, decoded)
+	}
+
+	// The legacy encoder writes strings the same way, so they read back
+	encodeOpts := DefaultEncodeOptions()
+	encodeOpts.SpecVersion = SpecVersionLegacy
+	encoded, err := EncodeWithOptions(expected, encodeOpts)
+	require.NoError(t, err)
+
+	decodeOpts := DefaultDecodeOptions()
+	decodeOpts.SpecVersion = SpecVersionLegacy
+	var decoded map[string]interface{}
+	require.NoError(t, DecodeWithOptions(encoded, &decoded, decodeOpts))
+	assert.Equal(t, expected, decoded)
+
+	_, err = EncodeWithOptions(map[string]string{"a": "line\nbreak"}, encodeOpts)
+	assert.Error(t, err)
+}
+
+func TestLegacySpecBaselineEmptyValues(t *testing.T) {
+	// Written by toonify 1.0, which put an empty array or object below its
+	// key at the key's own indentation
+	tests := []struct {
+		name     string
+		baseline string
+		expected map[string]interface{}
+	}{
+		{"empty array field", "root:\n    a:\n    []", map[string]interface{}{
+			"root": map[string]interface{}{"a": []interface{}{}},
+		}},
+		{"empty object field", "root:\n    a:\n    {}", map[string]interface{}{
+			"root": map[string]interface{}{"a": map[string]interface{}{}},
+		}},
+		{"empty list items", "l:\n    -\n    {}\n    -\n    []", map[string]interface{}{
+			"l": []interface{}{map[string]interface{}{}, []interface{}{}},
+		}},
+		{"mixed", "l:\n    -\n    []\n    -\n    {}\nd:\n  []\nroot:\n    a:\n    []\n    b:\n    {}\n    c: 1", map[string]interface{}{
+			"l":    []interface{}{[]interface{}{}, map[string]interface{}{}},
+			"d":    []interface{}{},
+			"root": map[string]interface{}{"a": []interface{}{}, "b": map[string]interface{}{}, "c": int64(1)},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, strict := range []bool{true, false} {
+				opts := DefaultDecodeOptions()
+				opts.SpecVersion = SpecVersionLegacy
+				opts.Strict = strict
+
+				var decoded map[string]interface{}
+				require.NoError(t, DecodeWithOptions(tt.baseline, &decoded, opts))
+				assert.Equal(t, tt.expected, decoded)
+			}
+		})
+	}
+}
+
+func TestPreserveOrderRoundtrip(t *testing.T) {
+	input := "zeta: 1\nalpha:\n  y: 2\n  x: 3\nrows[2]{name,id}:\n  a,1\n  b,2"
+
+	opts := DefaultDecodeOptions()
+	opts.PreserveOrder = true
+	var decoded interface{}
+	require.NoError(t, DecodeWithOptions(input, &decoded, opts))
+
+	jsonData, err := json.Marshal(decoded)
+	require.NoError(t, err)
+	assert.Equal(t, `{"zeta":1,"alpha":{"y":2,"x":3},"rows":[{"name":"a","id":1},{"name":"b","id":2}]}`, string(jsonData))
+
+	fromJSON, err := UnmarshalOrderedJSON(jsonData)
+	require.NoError(t, err)
+	encoded, err := Encode(fromJSON)
+	require.NoError(t, err)
+	assert.Equal(t, input, encoded)
+}
+
+func TestStreamEncoder(t *testing.T) {
+	type user struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	require.NoError(t, enc.Encode([]user{{1, "Alice"}, {2, "Bob"}}))
+	assert.Equal(t, "[2]{id,name}:\n  1,Alice\n  2,Bob\n", buf.String())
+
+	buf.Reset()
+	opts := DefaultEncodeOptions()
+	opts.Delimiter = DelimiterPipe
+	enc.SetOptions(opts)
+	require.NoError(t, enc.Encode(map[string][]int{"ids": {1, 2}}))
+	assert.Equal(t, "ids[2|]: 1|2\n", buf.String())
+}
+
+func TestStreamDecoder(t *testing.T) {
+	type user struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	users := []user{{1, "Alice"}, {2, "Bob"}}
+
+	var buf bytes.Buffer
+	require.NoError(t, NewEncoder(&buf).Encode(map[string]interface{}{"users": users}))
+
+	var decoded struct {
+		Users []user `json:"users"`
+	}
+	require.NoError(t, NewDecoder(&buf).Decode(&decoded))
+	assert.Equal(t, users, decoded.Users)
+
+	dec := NewDecoder(strings.NewReader("[2|]: 1|2"))
+	opts := DefaultDecodeOptions()
+	opts.Strict = false
+	dec.SetOptions(opts)
+	var ids []int
+	require.NoError(t, dec.Decode(&ids))
+	assert.Equal(t, []int{1, 2}, ids)
+}